@@ -0,0 +1,142 @@
+package papergres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxBindParams is the most bind parameters postgres accepts in a single
+// statement. Bulk inserts are split into batches that stay under it.
+const maxBindParams = 65535
+
+// BulkInsert inserts a slice of objects using multi-row
+// INSERT ... VALUES (...), (...) statements, sending as many rows per round
+// trip as the postgres parameter limit allows. This is much faster than
+// InsertAll for large slices.
+//
+// objs must be a slice with items in it.
+// the Result slice will be in the same order as objs, so the same loop as with
+// InsertAll sets all the primary keys:
+//
+//	for i, r := range results {
+//		objs[i].Id = r.LastInsertId.ID
+//	}
+//
+// This relies on postgres returning the ids of a multi-row insert in the
+// order the rows were given, which it does but does not document.
+//
+// Every row of a batch shares the batch's ExecutionTime and Err. A failed
+// batch does not stop later batches from running, and the returned
+// *RepeatError lists the index of every row of the failed batches.
+func (s *Schema) BulkInsert(objs interface{}) ([]*Result, error) {
+	slice, err := convertToSlice(objs)
+	if err != nil {
+		return nil, err
+	}
+	if len(slice) == 0 {
+		return nil, errors.New("empty slice")
+	}
+
+	fields, primary := prepareFields(slice[0], false)
+	if primary == nil {
+		return nil, errNoPrimaryKey
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("no columns to insert")
+	}
+
+	size := maxBindParams / len(fields)
+	batches := (len(slice) + size - 1) / size
+	results := make([]*Result, len(slice))
+	errs := make([]error, len(slice))
+
+	for b := 0; b < batches; b++ {
+		start := b * size
+		end := start + size
+		if end > len(slice) {
+			end = len(slice)
+		}
+		batch := slice[start:end]
+
+		var args []interface{}
+		for _, obj := range batch {
+			args = append(args, insertArgs(obj, false)...)
+		}
+		q := s.query(bulkInsertSQL(batch[0], s.Name, len(batch)), args...)
+		q.omitArgs = true
+
+		var ids []meta
+		cmd := func(ctx context.Context, r *Result) error {
+			ids = nil
+			db, err := q.executor()
+			if err != nil {
+				return err
+			}
+			if err = db.SelectContext(ctx, &ids, q.SQL, q.Args...); err != nil {
+				return err
+			}
+			r.RowsReturned = len(ids)
+			r.RowsAffected.Count = int64(len(ids))
+			if len(ids) != len(batch) {
+				return fmt.Errorf("bulk insert returned %d ids for %d rows", len(ids), len(batch))
+			}
+			return nil
+		}
+		res := execCommand(q, cmd, LogField{"bulk_batch", b + 1}, LogField{"bulk_batches", batches},
+			LogField{"bulk_rows", len(batch)})
+
+		// the i-th id is taken to belong to the i-th object of the batch.
+		// postgres returns the rows of a multi-row VALUES insert in input
+		// order, but its documentation doesn't promise it: we rely on
+		// undocumented behavior here
+		for i := range batch {
+			r := NewResult()
+			r.ExecutionTime = res.ExecutionTime
+			r.Err = res.Err
+			errs[start+i] = res.Err
+			if res.Err == nil {
+				r.setMeta(meta{LastInsertId: ids[i].LastInsertId, RowsAffected: 1})
+			}
+			results[start+i] = r
+		}
+	}
+
+	return results, newRepeatError(errs)
+}
+
+// bulkInsertSQL generates a multi-row insert SQL string for n objects shaped
+// like obj. Placeholders are numbered row by row, matching the order of the
+// concatenated insertArgs of each object.
+func bulkInsertSQL(obj interface{}, schema string, n int) string {
+	fields, primary := prepareFields(obj, false)
+
+	sql := fmt.Sprintf("INSERT INTO %s (", tableName(obj, schema))
+	for _, f := range fields {
+		sql += fmt.Sprintf("\n\t%s,", getColumnName(f))
+	}
+	sql = strings.TrimRight(sql, ",")
+	sql += "\n)\nVALUES"
+
+	var b strings.Builder
+	b.WriteString(sql)
+	p := 1
+	for i := 0; i < n; i++ {
+		b.WriteString("\n\t(")
+		for j := range fields {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "$%v", p)
+			p++
+		}
+		b.WriteString(")")
+		if i < n-1 {
+			b.WriteString(",")
+		}
+	}
+	fmt.Fprintf(&b, "\nRETURNING %s as LastInsertId;", getColumnName(primary))
+
+	return b.String()
+}
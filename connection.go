@@ -2,7 +2,6 @@ package papergres
 
 import (
 	"fmt"
-	"net"
 	"net/url"
 	"sort"
 	"strings"
@@ -36,106 +35,202 @@ type Connection struct {
 	SSLCert     string
 	SSLKey      string
 	SSLRootCert string
+
+	// ApplicationName is reported to the server as application_name. Unlike
+	// AppName, which is only a fallback, it overrides the PGAPPNAME
+	// environment variable.
+	ApplicationName string
+
+	// SearchPath sets the schema search_path for every connection.
+	SearchPath string
+
+	// Params holds any other connection parameters, passed to the server as
+	// is.
+	Params map[string]string
+
+	// Service names a section of the connection service file to fill unset
+	// fields from. It is only read by Resolve.
+	Service string
+
+	// Driver is the name of the registered database/sql driver to connect
+	// with. It defaults to "postgres", the lib/pq driver, so other drivers
+	// can be imported in the same binary without being picked up by mistake.
+	Driver string
+
+	// Pool tunes the connection pool, it is applied when the pool is opened.
+	Pool PoolConfig
 }
 
-// NewConnection creates and returns the Connection object to the postgres server
+// NewConnection creates and returns the Connection object to the postgres server.
+// databaseURL can be a postgres:// URL or a key=value connection string, see
+// ParseConnection. A non-empty appName overrides fallback_application_name.
+// It panics if databaseURL cannot be parsed, use ParseConnection to get an
+// error instead.
 func NewConnection(databaseURL string, appName string) Connection {
-	u, err := url.Parse(databaseURL)
+	conn, err := ParseConnection(databaseURL)
 	if err != nil {
 		panic(err)
 	}
-
-	host, port, _ := net.SplitHostPort(u.Host)
-	p, _ := u.User.Password()
-	q, _ := url.ParseQuery(u.RawQuery)
-	path := u.Path
-	if strings.Index(path, "/") == 0 {
-		path = path[1:]
-	}
-
-	// Set sslmode
-	sslMode := setSSLMode(q)
-
-	// Build the Connection object
-	conn := Connection{
-		User:     u.User.Username(),
-		Password: p,
-		Host:     host,
-		Port:     port,
-		Database: path,
-		AppName:  appName,
-		SSLMode:  sslMode,
+	if appName != "" {
+		conn.AppName = appName
 	}
-
 	return conn
 }
 
 // String method builds a DSN(Data Source Name) connection string based on the
-// given database connection settings and returns it.
+// given database connection settings and returns it. Values are quoted and
+// escaped as needed so the result can be parsed back with ParseConnection.
 func (conn *Connection) String() string {
 	var s string
 	if conn.Database != "" {
-		s += fmt.Sprintf("dbname=%s ", conn.Database)
+		s += fmt.Sprintf("dbname=%s ", quoteDSNValue(conn.Database))
 	}
 	if conn.User != "" {
-		s += fmt.Sprintf("user=%s ", conn.User)
+		s += fmt.Sprintf("user=%s ", quoteDSNValue(conn.User))
 	}
 	if conn.Password != "" {
-		s += fmt.Sprintf("password=%s ", conn.Password)
+		s += fmt.Sprintf("password=%s ", quoteDSNValue(conn.Password))
 	}
 	if conn.Host != "" {
-		s += fmt.Sprintf("host=%s ", conn.Host)
+		s += fmt.Sprintf("host=%s ", quoteDSNValue(conn.Host))
 	}
 	if conn.Port != "" {
-		s += fmt.Sprintf("port=%s ", conn.Port)
+		s += fmt.Sprintf("port=%s ", quoteDSNValue(conn.Port))
 	}
 	if conn.AppName != "" {
-		s += fmt.Sprintf("fallback_application_name=%s ", conn.AppName)
+		s += fmt.Sprintf("fallback_application_name=%s ", quoteDSNValue(conn.AppName))
+	}
+	if conn.ApplicationName != "" {
+		s += fmt.Sprintf("application_name=%s ", quoteDSNValue(conn.ApplicationName))
 	}
 	if conn.SSLMode != "" {
-		s += fmt.Sprintf("sslmode=%s ", conn.SSLMode)
+		s += fmt.Sprintf("sslmode=%s ", quoteDSNValue(string(conn.SSLMode)))
 	}
 	if conn.SSLCert != "" {
-		s += fmt.Sprintf("sslcert=%s ", conn.SSLCert)
+		s += fmt.Sprintf("sslcert=%s ", quoteDSNValue(conn.SSLCert))
 	}
 	if conn.SSLKey != "" {
-		s += fmt.Sprintf("sslkey=%s ", conn.SSLKey)
+		s += fmt.Sprintf("sslkey=%s ", quoteDSNValue(conn.SSLKey))
 	}
 	if conn.SSLRootCert != "" {
-		s += fmt.Sprintf("sslrootcert=%s ", conn.SSLRootCert)
+		s += fmt.Sprintf("sslrootcert=%s ", quoteDSNValue(conn.SSLRootCert))
+	}
+	if conn.SearchPath != "" {
+		s += fmt.Sprintf("search_path=%s ", quoteDSNValue(conn.SearchPath))
+	}
+	for _, k := range conn.paramKeys() {
+		s += fmt.Sprintf("%s=%s ", k, quoteDSNValue(conn.Params[k]))
 	}
 	s += fmt.Sprintf("connect_timeout=%v ", conn.Timeout)
 	return s
 }
 
-// NewDatabase creates a new Database object
-func (conn Connection) NewDatabase() *Database {
-	return &Database{
-		conn: &conn,
+// URL renders the connection as a postgres:// URL that can be parsed back
+// with ParseConnection.
+func (conn *Connection) URL() string {
+	u := "postgres://"
+	if conn.User != "" || conn.Password != "" {
+		u += escapeURLPart(conn.User)
+		if conn.Password != "" {
+			u += ":" + escapeURLPart(conn.Password)
+		}
+		u += "@"
 	}
-}
 
-// function to set SSL mode for connection based on url.Values
-func setSSLMode(q url.Values) SSLMode {
-	sslMode := SSLDisable
+	q := url.Values{}
+	hosts := strings.Split(conn.Host, ",")
+	ports := strings.Split(conn.Port, ",")
+	if len(ports) != len(hosts) {
+		// a single port for many hosts applies to all of them
+		if conn.Port != "" {
+			q.Set("port", conn.Port)
+		}
+		ports = make([]string, len(hosts))
+	}
+	for i, h := range hosts {
+		if i > 0 {
+			u += ","
+		}
+		if strings.Contains(h, ":") {
+			u += "[" + h + "]"
+		} else {
+			u += escapeURLPart(h)
+		}
+		if ports[i] != "" {
+			u += ":" + ports[i]
+		}
+	}
+	u += "/" + escapeURLPart(conn.Database)
 
-	if len(q["sslmode"]) > 0 && q["sslmode"][0] != "" {
-		sslMode = SSLMode(q["sslmode"][0])
+	if conn.AppName != "" {
+		q.Set("fallback_application_name", conn.AppName)
+	}
+	if conn.ApplicationName != "" {
+		q.Set("application_name", conn.ApplicationName)
 	}
+	if conn.Timeout != 0 {
+		q.Set("connect_timeout", fmt.Sprint(conn.Timeout))
+	}
+	if conn.SSLMode != "" {
+		q.Set("sslmode", string(conn.SSLMode))
+	}
+	if conn.SSLCert != "" {
+		q.Set("sslcert", conn.SSLCert)
+	}
+	if conn.SSLKey != "" {
+		q.Set("sslkey", conn.SSLKey)
+	}
+	if conn.SSLRootCert != "" {
+		q.Set("sslrootcert", conn.SSLRootCert)
+	}
+	if conn.SearchPath != "" {
+		q.Set("search_path", conn.SearchPath)
+	}
+	for k, v := range conn.Params {
+		q.Set(k, v)
+	}
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return u
+}
 
-	return sslMode
+// paramKeys returns the keys of Params in sorted order.
+func (conn *Connection) paramKeys() []string {
+	keys := make([]string, 0, len(conn.Params))
+	for k := range conn.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// NewDatabase creates a new Database object
+func (conn Connection) NewDatabase() *Database {
+	return &Database{
+		conn:          &conn,
+		logSampleRate: 1,
+	}
 }
 
 // prettifyConnString prints out all the props from connection string in a neat
-// way.
+// way. Passwords and SSL keys are masked.
 func prettifyConnString(conn string) string {
-	var str string
-	props := strings.Split(conn, " ")
+	pairs, err := splitDSN(conn)
+	if err != nil {
+		// never print what we can't parse, it may hold a password
+		return redacted
+	}
+
+	props := make([]string, len(pairs))
+	for i, p := range pairs {
+		props[i] = fmt.Sprintf("%s=%s", p[0], quoteDSNValue(redactParam(p[0], p[1])))
+	}
 	sort.Strings(props)
+
+	var str string
 	for _, s := range props {
-		if s != "" {
-			str += fmt.Sprintf("\n\t%s", s)
-		}
+		str += fmt.Sprintf("\n\t%s", s)
 	}
 	return str
 }
@@ -0,0 +1,161 @@
+package papergres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// CopyFrom loads a slice of objects into their table using postgres'
+// COPY FROM STDIN, which is the fastest way to load large amounts of data.
+// objs must be a slice with items in it. The columns are derived from the
+// objects the same way inserts are, so the primary key is left to the
+// database to generate.
+//
+// The copy runs in its own transaction, or in the schema's transaction when
+// created from a Tx, so either every row is loaded or none are.
+// Result.RowsAffected holds the number of rows copied. No primary keys are
+// returned. The copy is never retried, whatever the RetryPolicy.
+func (s *Schema) CopyFrom(objs interface{}) *Result {
+	slice, err := convertToSlice(objs)
+	if err != nil {
+		return newErrResult(err)
+	}
+	if len(slice) == 0 {
+		return newErrResult(errors.New("empty slice"))
+	}
+
+	i := 0
+	next := func(ctx context.Context) (interface{}, bool, error) {
+		if i == len(slice) {
+			return nil, false, nil
+		}
+		i++
+		return slice[i-1], true, nil
+	}
+	return s.copyFrom(next)
+}
+
+// CopyFromChannel works like CopyFrom but streams the objects from ch, which
+// must be a channel of structs or struct pointers. Rows are sent to the
+// database as they are received and the copy completes once ch is closed, so
+// the full data set never has to be held in memory. Waiting on ch stops with a
+// *CanceledError when the schema's context is done. Like CopyFrom it is never
+// retried, since the rows already received are gone.
+func (s *Schema) CopyFromChannel(ch interface{}) *Result {
+	v := reflect.ValueOf(ch)
+	if v.Kind() != reflect.Chan || v.Type().ChanDir()&reflect.RecvDir == 0 {
+		return newErrResult(errors.New("value is not a receivable channel"))
+	}
+
+	next := func(ctx context.Context) (interface{}, bool, error) {
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: v},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		}
+		chosen, obj, ok := reflect.Select(cases)
+		if chosen == 1 {
+			return nil, false, ctx.Err()
+		}
+		if !ok {
+			return nil, false, nil
+		}
+		return obj.Interface(), true, nil
+	}
+	return s.copyFrom(next)
+}
+
+// copyFrom reads the first object to derive the table and columns, then
+// streams it and every following object returned by next into a COPY
+// statement.
+func (s *Schema) copyFrom(next func(ctx context.Context) (interface{}, bool, error)) *Result {
+	// the SQL is set once the first object tells us the table
+	q := s.query("")
+	first, ok, err := next(q.context())
+	if err != nil {
+		if ctxErr := q.context().Err(); ctxErr != nil {
+			return newErrResult(&CanceledError{Ctx: ctxErr})
+		}
+		return newErrResult(err)
+	}
+	if !ok {
+		return newErrResult(errors.New("nothing to copy"))
+	}
+
+	fields, _ := prepareFields(first, false)
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = getColumnName(f)
+	}
+	table := goToSQLName(getTypeName(first))
+	q.SQL = pq.CopyInSchema(s.Name, table, columns...)
+	// the rows sent by a failed attempt can't be read again
+	q.noRetry = true
+
+	cmd := func(ctx context.Context, r *Result) (err error) {
+		tx := q.tx
+		if tx == nil {
+			if tx, err = q.Database.BeginTx(ctx, nil); err != nil {
+				return err
+			}
+			defer func() {
+				if err != nil {
+					tx.tx.Rollback()
+					return
+				}
+				err = tx.tx.Commit()
+			}()
+		}
+
+		stmt, err := tx.tx.PreparexContext(ctx, q.SQL)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		count, err := copyRows(ctx, stmt, first, next)
+		if err != nil {
+			return err
+		}
+
+		// an exec without args flushes the buffered rows and completes the copy
+		res, err := stmt.ExecContext(ctx)
+		if err != nil {
+			return err
+		}
+		r.RowsAffected.Count, err = res.RowsAffected()
+		if err != nil {
+			r.RowsAffected.Count = count
+		}
+		return nil
+	}
+
+	return execCommand(q, cmd)
+}
+
+// copyRows sends first and every object returned by next to the copy
+// statement and returns the number of rows sent.
+func copyRows(ctx context.Context, stmt *sqlx.Stmt, first interface{},
+	next func(ctx context.Context) (interface{}, bool, error)) (int64, error) {
+	typ := reflect.TypeOf(first)
+	var count int64
+	for obj, ok := first, true; ok; {
+		if t := reflect.TypeOf(obj); t != typ {
+			return count, fmt.Errorf("cannot copy %v with %v rows", t, typ)
+		}
+		if _, err := stmt.ExecContext(ctx, insertArgs(obj, false)...); err != nil {
+			return count, err
+		}
+		count++
+
+		var err error
+		if obj, ok, err = next(ctx); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
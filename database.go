@@ -1,8 +1,13 @@
 package papergres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 // Database contains all required database attributes
@@ -10,8 +15,22 @@ type Database struct {
 	// conn is all information needed to connect to the database.
 	conn *Connection
 
-	// connString referes to the DSN string for the current DB.
-	connString string
+	// retry is the retry policy for every query of the database.
+	retry *RetryPolicy
+
+	// redactArg masks query arguments before they are logged.
+	redactArg ArgRedactor
+
+	// slowQuery is the execution time above which queries are logged at
+	// warn level. 0 disables the slow query log.
+	slowQuery time.Duration
+
+	// logSampleRate is the fraction of successful, fast queries that are
+	// logged.
+	logSampleRate float64
+
+	// hooks are called around every query of the database.
+	hooks []Hook
 }
 
 // Connection returns the connection information for a database
@@ -22,11 +41,9 @@ func (db *Database) Connection() Connection {
 // ConnectionString returns the DSN(Data Source Name) connection string for the
 // current DB connection.
 func (db *Database) ConnectionString() string {
-	if db.connString != "" {
-		return db.connString
-	}
-	db.connString = db.conn.String()
-	return db.connString
+	// built on every call rather than cached, which would race when the
+	// database is used from many goroutines
+	return db.conn.String()
 }
 
 // CreateDatabase creates a default database
@@ -51,7 +68,6 @@ func (db *Database) CreateDatabase() *Result {
 	conn := db.Connection()
 	conn.Database = ""
 	db.conn = &conn
-	db.connString = ""
 	return db.Query(sql).ExecNonQuery()
 }
 
@@ -65,6 +81,57 @@ func (db *Database) Insert(obj interface{}) *Result {
 	return db.Schema("public").Insert(obj)
 }
 
+// GenerateUpdate generates an update query for the given object
+func (db *Database) GenerateUpdate(obj interface{}) (*Query, error) {
+	return db.Schema("public").GenerateUpdate(obj)
+}
+
+// Update updates the row matching the passed in object's primary key
+func (db *Database) Update(obj interface{}) *Result {
+	return db.Schema("public").Update(obj)
+}
+
+// Delete deletes the row matching the passed in object's primary key
+func (db *Database) Delete(obj interface{}) *Result {
+	return db.Schema("public").Delete(obj)
+}
+
+// DeleteByPK deletes the row with the given primary key from obj's table
+func (db *Database) DeleteByPK(obj interface{}, id PrimaryKey) *Result {
+	return db.Schema("public").DeleteByPK(obj, id)
+}
+
+// FindByPK fetches the row with the given primary key into dest
+func (db *Database) FindByPK(dest interface{}, id PrimaryKey) *Result {
+	return db.Schema("public").FindByPK(dest, id)
+}
+
+// BulkInsert inserts a slice of objects using batched multi-row inserts.
+// the Result slice will be in the same order as objs
+func (db *Database) BulkInsert(objs interface{}) ([]*Result, error) {
+	return db.Schema("public").BulkInsert(objs)
+}
+
+// CopyFrom loads a slice of objects using COPY FROM STDIN
+func (db *Database) CopyFrom(objs interface{}) *Result {
+	return db.Schema("public").CopyFrom(objs)
+}
+
+// CopyFromChannel loads the objects received on ch using COPY FROM STDIN
+func (db *Database) CopyFromChannel(ch interface{}) *Result {
+	return db.Schema("public").CopyFromChannel(ch)
+}
+
+// Upsert inserts the passed in object or updates the row it conflicts with
+func (db *Database) Upsert(obj interface{}, opts UpsertOptions) *Result {
+	return db.Schema("public").Upsert(obj, opts)
+}
+
+// UpsertAll upserts a slice of objects with the same options
+func (db *Database) UpsertAll(objs interface{}, opts UpsertOptions) ([]*Result, error) {
+	return db.Schema("public").UpsertAll(objs, opts)
+}
+
 // InsertAll inserts a slice of objects concurrently.
 // objs must be a slice with items in it.
 // the Result slice will be in the same order as objs
@@ -78,7 +145,65 @@ func (db *Database) InsertAll(objs interface{}) ([]*Result, error) {
 
 // Ping tests the database connection
 func (db *Database) Ping() error {
-	return open(db.ConnectionString()).Ping()
+	return db.PingContext(context.Background())
+}
+
+// PingContext tests the database connection, giving up when ctx is done.
+func (db *Database) PingContext(ctx context.Context) error {
+	pool, err := db.pool()
+	if err != nil {
+		return err
+	}
+	return pool.PingContext(ctx)
+}
+
+// Open opens the connection pool for the database. It returns an error if the
+// connection's driver is not registered or the pool cannot be created, but it
+// does not connect to the server; use Ping for that.
+//
+// Calling Open is optional, the pool is opened by the first query otherwise.
+func (db *Database) Open() error {
+	_, err := db.pool()
+	return err
+}
+
+// Close closes the connection pool for the database's connection. Any other
+// Database with the same connection shares the pool, so it is closed for them
+// as well and reopened by their next query.
+func (db *Database) Close() error {
+	key, err := db.key()
+	if err != nil {
+		return err
+	}
+	sdb := openDBs.remove(key)
+	if sdb == nil {
+		return nil
+	}
+	return sdb.Close()
+}
+
+// pool returns the cached DB that manages the database's connection pool,
+// opening it if needed.
+func (db *Database) pool() (*sqlx.DB, error) {
+	key, err := db.key()
+	if err != nil {
+		return nil, err
+	}
+	return openDBs.open(key, *db.conn)
+}
+
+// key returns the registry key of the database's pool. A connection with an
+// empty Driver uses the default postgres driver, which can't dial more than
+// one host.
+func (db *Database) key() (dbKey, error) {
+	driver, err := getDriver(db.conn.Driver)
+	if err != nil {
+		return dbKey{}, err
+	}
+	if driver == defaultDriver && strings.Contains(db.conn.Host, ",") {
+		return dbKey{}, fmt.Errorf("%w: %s", errMultipleHosts, db.conn.Host)
+	}
+	return dbKey{driver, db.ConnectionString()}, nil
 }
 
 // Query creates a base new query object that can be used for all database operations
@@ -90,9 +215,25 @@ func (db *Database) Query(sql string, args ...interface{}) *Query {
 	}
 }
 
-// Stats returns DBStats. Right now this only returns OpenConnections
-func (db *Database) Stats() sql.DBStats {
-	return open(db.ConnectionString()).Stats()
+// Stats returns the current state of the database's connection pool.
+func (db *Database) Stats() PoolStats {
+	pool, err := db.pool()
+	if err != nil {
+		return newPoolStats(db.conn, sql.DBStats{})
+	}
+	return newPoolStats(db.conn, pool.Stats())
+}
+
+// WithPool sets the pool configuration of the database's connection. It is
+// applied when the pool is opened, or right away if it is already open.
+func (db *Database) WithPool(p PoolConfig) *Database {
+	db.conn.Pool = p
+	if key, err := db.key(); err == nil {
+		if sdb := openDBs.get(key); sdb != nil {
+			p.apply(sdb)
+		}
+	}
+	return db
 }
 
 // Schema allows for certain operations that require a specific schema
@@ -0,0 +1,271 @@
+package papergres
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// envParams maps the libpq environment variables to the connection parameter
+// they set.
+var envParams = []struct{ env, key string }{
+	{"PGHOST", "host"},
+	{"PGPORT", "port"},
+	{"PGDATABASE", "dbname"},
+	{"PGUSER", "user"},
+	{"PGPASSWORD", "password"},
+	{"PGAPPNAME", "application_name"},
+	{"PGCONNECT_TIMEOUT", "connect_timeout"},
+	{"PGSSLMODE", "sslmode"},
+	{"PGSSLCERT", "sslcert"},
+	{"PGSSLKEY", "sslkey"},
+	{"PGSSLROOTCERT", "sslrootcert"},
+	{"PGOPTIONS", "options"},
+}
+
+// ConnectionFromEnv builds a Connection from the standard PG* environment
+// variables, a service file and the password file the same way libpq does.
+// It is shorthand for resolving an empty Connection, see Connection.Resolve.
+func ConnectionFromEnv() (Connection, error) {
+	return Connection{}.Resolve()
+}
+
+// Resolve returns a copy of conn with every field that is not set filled in
+// from, in order of precedence:
+//
+//  1. the service named by conn.Service or PGSERVICE, read from PGSERVICEFILE
+//     or ~/.pg_service.conf, then from pg_service.conf in PGSYSCONFDIR
+//  2. the PG* environment variables, e.g. PGHOST, PGPORT, PGUSER, PGPASSWORD,
+//     PGDATABASE and PGSSLMODE
+//  3. for the password only, the first matching line of PGPASSFILE or
+//     ~/.pgpass (%APPDATA%\postgresql\pgpass.conf on Windows)
+//
+// Fields set on conn are never overridden. A password file that can be read
+// by group or others is ignored, as libpq does.
+func (conn Connection) Resolve() (Connection, error) {
+	service := conn.Service
+	if service == "" {
+		service = os.Getenv("PGSERVICE")
+	}
+	if service != "" {
+		svc, err := lookupService(service)
+		if err != nil {
+			return Connection{}, err
+		}
+		conn.fill(svc)
+	}
+
+	var env Connection
+	for _, p := range envParams {
+		if v, ok := os.LookupEnv(p.env); ok {
+			if err := env.set(p.key, v); err != nil {
+				return Connection{}, fmt.Errorf("%s: %w", p.env, err)
+			}
+		}
+	}
+	conn.fill(env)
+
+	if conn.Password == "" {
+		password, err := lookupPassword(conn)
+		if err != nil {
+			return Connection{}, err
+		}
+		conn.Password = password
+	}
+
+	return conn, nil
+}
+
+// fill sets every field of conn that is not set to its value in d.
+func (conn *Connection) fill(d Connection) {
+	fill := func(field *string, v string) {
+		if *field == "" {
+			*field = v
+		}
+	}
+	fill(&conn.Database, d.Database)
+	fill(&conn.User, d.User)
+	fill(&conn.Password, d.Password)
+	fill(&conn.Host, d.Host)
+	fill(&conn.Port, d.Port)
+	fill(&conn.AppName, d.AppName)
+	fill(&conn.SSLCert, d.SSLCert)
+	fill(&conn.SSLKey, d.SSLKey)
+	fill(&conn.SSLRootCert, d.SSLRootCert)
+	fill(&conn.ApplicationName, d.ApplicationName)
+	fill(&conn.SearchPath, d.SearchPath)
+	if conn.SSLMode == "" {
+		conn.SSLMode = d.SSLMode
+	}
+	if conn.Timeout == 0 {
+		conn.Timeout = d.Timeout
+	}
+	for k, v := range d.Params {
+		if _, ok := conn.Params[k]; !ok {
+			if conn.Params == nil {
+				conn.Params = make(map[string]string)
+			}
+			conn.Params[k] = v
+		}
+	}
+}
+
+// lookupService reads the connection parameters of the named service from
+// the user's service file, falling back to the system wide one.
+func lookupService(name string) (Connection, error) {
+	var files []string
+	if f := os.Getenv("PGSERVICEFILE"); f != "" {
+		files = append(files, f)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".pg_service.conf"))
+	}
+	if dir := os.Getenv("PGSYSCONFDIR"); dir != "" {
+		files = append(files, filepath.Join(dir, "pg_service.conf"))
+	}
+
+	for _, f := range files {
+		conn, found, err := readService(f, name)
+		if err != nil {
+			return Connection{}, err
+		}
+		if found {
+			return conn, nil
+		}
+	}
+	return Connection{}, fmt.Errorf("%w: %s", errUnknownService, name)
+}
+
+// readService reads the named service from an INI style service file. A
+// missing file is not an error.
+func readService(file, name string) (conn Connection, found bool, err error) {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return Connection{}, false, nil
+	}
+	if err != nil {
+		return Connection{}, false, err
+	}
+	defer f.Close()
+
+	var section string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		l := strings.TrimSpace(scanner.Text())
+		switch {
+		case l == "" || l[0] == '#':
+			continue
+		case l[0] == '[' && l[len(l)-1] == ']':
+			if found {
+				// done with the service we were looking for
+				return conn, true, nil
+			}
+			section = l[1 : len(l)-1]
+			found = section == name
+			continue
+		case section != name:
+			continue
+		}
+
+		key, val, ok := strings.Cut(l, "=")
+		if !ok {
+			return Connection{}, false, fmt.Errorf("%s:%d: missing \"=\"", file, line)
+		}
+		if err := conn.set(strings.TrimSpace(key), strings.TrimSpace(val)); err != nil {
+			return Connection{}, false, fmt.Errorf("%s:%d: %w", file, line, err)
+		}
+	}
+	return conn, found, scanner.Err()
+}
+
+// lookupPassword returns the password for conn from the password file, or ""
+// if there is no matching entry.
+func lookupPassword(conn Connection) (string, error) {
+	file := os.Getenv("PGPASSFILE")
+	if file == "" {
+		if runtime.GOOS == "windows" {
+			file = filepath.Join(os.Getenv("APPDATA"), "postgresql", "pgpass.conf")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			file = filepath.Join(home, ".pgpass")
+		} else {
+			return "", nil
+		}
+	}
+
+	info, err := os.Stat(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		logDebug(fmt.Sprintf("password file %s has group or world access; permissions should be u=rw (0600) or less", file))
+		return "", nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// the values libpq matches against when they are not set
+	host := conn.Host
+	if host == "" || strings.HasPrefix(host, "/") {
+		host = "localhost"
+	}
+	port := conn.Port
+	if port == "" {
+		port = "5432"
+	}
+	database := conn.Database
+	if database == "" {
+		database = conn.User
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := splitPassLine(line)
+		if len(fields) != 5 {
+			continue
+		}
+		if matchPass(fields[0], host) && matchPass(fields[1], port) &&
+			matchPass(fields[2], database) && matchPass(fields[3], conn.User) {
+			return fields[4], nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// splitPassLine splits a password file line on its colons. A backslash
+// escapes a colon or backslash.
+func splitPassLine(line string) []string {
+	var fields []string
+	var field strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			i++
+			field.WriteByte(line[i])
+		case c == ':' && len(fields) < 4:
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(c)
+		}
+	}
+	return append(fields, field.String())
+}
+
+// matchPass reports whether a password file field matches v.
+func matchPass(field, v string) bool {
+	return field == "*" || field == v
+}
@@ -0,0 +1,116 @@
+package papergres
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clearPGEnv unsets every PG* variable Resolve reads for the test and points
+// the service and password files into a temp dir.
+func clearPGEnv(t *testing.T) string {
+	dir := t.TempDir()
+	for _, p := range envParams {
+		t.Setenv(p.env, "")
+		os.Unsetenv(p.env)
+	}
+	t.Setenv("PGSERVICE", "")
+	t.Setenv("PGSYSCONFDIR", "")
+	t.Setenv("PGSERVICEFILE", filepath.Join(dir, "pg_service.conf"))
+	t.Setenv("PGPASSFILE", filepath.Join(dir, "pgpass"))
+	return dir
+}
+
+func TestConnectionFromEnv(t *testing.T) {
+	clearPGEnv(t)
+	t.Setenv("PGHOST", "db.internal")
+	t.Setenv("PGPORT", "5433")
+	t.Setenv("PGUSER", "paper")
+	t.Setenv("PGPASSWORD", "secret")
+	t.Setenv("PGDATABASE", "paperchain")
+	t.Setenv("PGSSLMODE", "require")
+	t.Setenv("PGCONNECT_TIMEOUT", "4")
+
+	conn, err := ConnectionFromEnv()
+	assert.Nil(t, err, "ConnectionFromEnv")
+	assert.Equal(t, "db.internal", conn.Host, "host")
+	assert.Equal(t, "5433", conn.Port, "port")
+	assert.Equal(t, "paper", conn.User, "user")
+	assert.Equal(t, "secret", conn.Password, "password")
+	assert.Equal(t, "paperchain", conn.Database, "database")
+	assert.Equal(t, SSLRequire, conn.SSLMode, "sslmode")
+	assert.Equal(t, 4, conn.Timeout, "timeout")
+
+	// explicit fields win over the environment
+	conn, err = Connection{Host: "localhost", SSLMode: SSLDisable}.Resolve()
+	assert.Nil(t, err, "Resolve")
+	assert.Equal(t, "localhost", conn.Host, "explicit host")
+	assert.Equal(t, SSLDisable, conn.SSLMode, "explicit sslmode")
+	assert.Equal(t, "paper", conn.User, "user from env")
+
+	t.Setenv("PGCONNECT_TIMEOUT", "soon")
+	_, err = ConnectionFromEnv()
+	assert.NotNil(t, err, "invalid PGCONNECT_TIMEOUT")
+}
+
+func TestResolveService(t *testing.T) {
+	dir := clearPGEnv(t)
+	t.Setenv("PGHOST", "from-env")
+	t.Setenv("PGUSER", "env-user")
+
+	err := os.WriteFile(filepath.Join(dir, "pg_service.conf"), []byte(`
+# papergres services
+[other]
+host=other
+
+[paper]
+host = svc-host
+port=6432
+dbname=paperchain
+search_path=paper
+`), 0600)
+	assert.Nil(t, err, "write service file")
+
+	conn, err := Connection{Service: "paper", Port: "5432"}.Resolve()
+	assert.Nil(t, err, "Resolve")
+	assert.Equal(t, "svc-host", conn.Host, "host from service")
+	assert.Equal(t, "5432", conn.Port, "explicit port")
+	assert.Equal(t, "paperchain", conn.Database, "database from service")
+	assert.Equal(t, "paper", conn.SearchPath, "search path from service")
+	assert.Equal(t, "env-user", conn.User, "user from env")
+
+	t.Setenv("PGSERVICE", "missing")
+	_, err = ConnectionFromEnv()
+	assert.NotNil(t, err, "unknown service")
+}
+
+func TestResolvePassFile(t *testing.T) {
+	dir := clearPGEnv(t)
+	pgpass := filepath.Join(dir, "pgpass")
+	err := os.WriteFile(pgpass, []byte(`
+# host:port:database:username:password
+db1:5432:other:paper:wrong
+db1:*:paperchain:paper:pa\:ss\\word
+*:*:*:*:fallback
+`), 0600)
+	assert.Nil(t, err, "write pgpass")
+
+	conn, err := Connection{Host: "db1", User: "paper", Database: "paperchain"}.Resolve()
+	assert.Nil(t, err, "Resolve")
+	assert.Equal(t, `pa:ss\word`, conn.Password, "password from pgpass")
+
+	conn, err = Connection{Host: "db2", User: "paper", Password: "explicit"}.Resolve()
+	assert.Nil(t, err, "Resolve")
+	assert.Equal(t, "explicit", conn.Password, "explicit password")
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	assert.Nil(t, os.Chmod(pgpass, 0644), "chmod pgpass")
+	conn, err = Connection{Host: "db1", User: "paper", Database: "paperchain"}.Resolve()
+	assert.Nil(t, err, "Resolve")
+	assert.Equal(t, "", conn.Password, "world readable pgpass ignored")
+}
@@ -0,0 +1,231 @@
+package papergres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Sentinel errors for the postgres failures callers most often handle. A
+// *PGError matches the sentinel for its SQLSTATE with errors.Is, e.g.
+// errors.Is(res.Err, ErrUniqueViolation).
+var (
+	ErrUniqueViolation      = errors.New("unique violation")
+	ErrForeignKeyViolation  = errors.New("foreign key violation")
+	ErrNotNullViolation     = errors.New("not null violation")
+	ErrCheckViolation       = errors.New("check violation")
+	ErrSerializationFailure = errors.New("serialization failure")
+	ErrDeadlockDetected     = errors.New("deadlock detected")
+
+	// ErrNoRows is returned by ExecSingle when the query returns no rows.
+	// It is sql.ErrNoRows.
+	ErrNoRows = sql.ErrNoRows
+)
+
+// sqlStateErrs maps SQLSTATE codes to their sentinel error.
+var sqlStateErrs = map[string]error{
+	"23505": ErrUniqueViolation,
+	"23503": ErrForeignKeyViolation,
+	"23502": ErrNotNullViolation,
+	"23514": ErrCheckViolation,
+	"40001": ErrSerializationFailure,
+	"40P01": ErrDeadlockDetected,
+}
+
+// PGError is set as Result.Err whenever postgres rejects a query. It exposes
+// the details of the underlying *pq.Error, which it unwraps to.
+type PGError struct {
+	// Code is the five character SQLSTATE, e.g. 23505.
+	Code       string
+	Severity   string
+	Message    string
+	Detail     string
+	Hint       string
+	Schema     string
+	Table      string
+	Column     string
+	Constraint string
+
+	// Err is the original error from the driver.
+	Err *pq.Error
+}
+
+// newPGError creates a PGError from a driver error.
+func newPGError(err *pq.Error) *PGError {
+	return &PGError{
+		Code:       string(err.Code),
+		Severity:   err.Severity,
+		Message:    err.Message,
+		Detail:     err.Detail,
+		Hint:       err.Hint,
+		Schema:     err.Schema,
+		Table:      err.Table,
+		Column:     err.Column,
+		Constraint: err.Constraint,
+		Err:        err,
+	}
+}
+
+// Error implements the error interface.
+func (e *PGError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the original *pq.Error.
+func (e *PGError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the sentinel error for the SQLSTATE.
+func (e *PGError) Is(target error) bool {
+	sentinel, ok := sqlStateErrs[e.Code]
+	return ok && sentinel == target
+}
+
+// IsUniqueViolation reports whether err is a unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	return errors.Is(err, ErrUniqueViolation)
+}
+
+// IsForeignKeyViolation reports whether err is a foreign key violation.
+func IsForeignKeyViolation(err error) bool {
+	return errors.Is(err, ErrForeignKeyViolation)
+}
+
+// IsSerializationFailure reports whether err is a serialization failure of a
+// repeatable read or serializable transaction.
+func IsSerializationFailure(err error) bool {
+	return errors.Is(err, ErrSerializationFailure)
+}
+
+// IsDeadlock reports whether err is a detected deadlock.
+func IsDeadlock(err error) bool {
+	return errors.Is(err, ErrDeadlockDetected)
+}
+
+// IsNoRows reports whether err is the result of a query that was expected to
+// return a row but returned none.
+func IsNoRows(err error) bool {
+	return errors.Is(err, sql.ErrNoRows)
+}
+
+// SQLState returns the SQLSTATE code of the first postgres error in err's
+// chain, or an empty string if there is none.
+func SQLState(err error) string {
+	var pgErr *PGError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+	return ""
+}
+
+// classifyErr wraps a *pq.Error returned by the driver in a *PGError.
+// Any other error is returned as is.
+func classifyErr(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		return newPGError(pqErr)
+	}
+	return err
+}
+
+// ErrRepeatSkipped is the Result.Err of a Repeat iteration that was never
+// started because an earlier iteration failed and StopOnError was set.
+var ErrRepeatSkipped = errors.New("repeat iteration skipped after an earlier failure")
+
+// CanceledError is set as Result.Err when a query is stopped because its
+// context was canceled or its deadline passed. It unwraps to the context's
+// error and the driver's error, so errors.Is(err, context.Canceled) and
+// errors.Is(err, context.DeadlineExceeded) both hold.
+type CanceledError struct {
+	// Ctx is the error reported by the query's context.
+	Ctx error
+	// Err is the error returned by the driver when the query was interrupted.
+	// It is nil if the query never reached the database.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *CanceledError) Error() string {
+	if e.Err == nil || e.Err == e.Ctx {
+		return fmt.Sprintf("query canceled: %s", e.Ctx)
+	}
+	return fmt.Sprintf("query canceled: %s: %s", e.Ctx, e.Err)
+}
+
+// Unwrap returns the context's error and, if the query reached the database,
+// the driver's error.
+func (e *CanceledError) Unwrap() []error {
+	if e.Err == nil || e.Err == e.Ctx {
+		return []error{e.Ctx}
+	}
+	return []error{e.Ctx, e.Err}
+}
+
+// IsCanceled reports whether err is the result of a canceled context or an
+// exceeded deadline.
+func IsCanceled(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// IndexedError is the error of a single iteration of a Repeat, or of a single
+// object passed to InsertAll, UpsertAll or BulkInsert.
+type IndexedError struct {
+	// Index is the iteration, or the position of the object in the slice.
+	Index int
+	Err   error
+}
+
+// Error implements the error interface.
+func (e IndexedError) Error() string {
+	return fmt.Sprintf("index %d: %s", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e IndexedError) Unwrap() error {
+	return e.Err
+}
+
+// RepeatError is returned by operations that execute many statements, like
+// Repeat.Exec and Schema.InsertAll, when one or more of them fail. It holds
+// an IndexedError per failure, ordered by index.
+//
+// errors.Is and errors.As look through every failure, so
+// errors.As(err, &pqErr) finds the first *pq.Error.
+type RepeatError struct {
+	Errors []IndexedError
+}
+
+// Error implements the error interface. Each failure is on its own line.
+func (e *RepeatError) Error() string {
+	lines := make([]string, len(e.Errors))
+	for i, ie := range e.Errors {
+		lines[i] = ie.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the error of every failure.
+func (e *RepeatError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, ie := range e.Errors {
+		errs[i] = ie
+	}
+	return errs
+}
+
+// Indexes returns the indexes that failed, e.g. to retry exactly those rows.
+func (e *RepeatError) Indexes() []int {
+	idx := make([]int, len(e.Errors))
+	for i, ie := range e.Errors {
+		idx[i] = ie.Index
+	}
+	return idx
+}
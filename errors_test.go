@@ -0,0 +1,47 @@
+package papergres
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatErrorKeepsIndexes(t *testing.T) {
+	unique := &pq.Error{Code: "23505", Message: "duplicate key value", Constraint: "uc_book_title"}
+	other := errors.New("boom")
+
+	err := newRepeatError([]error{nil, unique, nil, other})
+	assert.EqualError(t, err, "index 1: pq: duplicate key value\nindex 3: boom")
+
+	var re *RepeatError
+	assert.True(t, errors.As(err, &re), "RepeatError")
+	assert.Equal(t, []int{1, 3}, re.Indexes(), "failed indexes")
+
+	var pqErr *pq.Error
+	assert.True(t, errors.As(err, &pqErr), "pq error")
+	assert.Equal(t, "uc_book_title", pqErr.Constraint, "constraint")
+	assert.True(t, errors.Is(err, other), "other error")
+
+	assert.Nil(t, newRepeatError([]error{nil, nil}), "no errors")
+}
+
+func TestClassifyErr(t *testing.T) {
+	err := classifyErr(&pq.Error{Code: "23505", Table: "book", Constraint: "uc_book_title"})
+
+	var pgErr *PGError
+	assert.True(t, errors.As(err, &pgErr), "PGError")
+	assert.Equal(t, "23505", pgErr.Code, "code")
+	assert.Equal(t, "book", pgErr.Table, "table")
+	assert.Equal(t, "uc_book_title", pgErr.Constraint, "constraint")
+	assert.Equal(t, "23505", SQLState(err), "SQLState")
+
+	assert.True(t, IsUniqueViolation(err), "unique violation")
+	assert.False(t, IsForeignKeyViolation(err), "foreign key violation")
+	assert.True(t, IsUniqueViolation(newRepeatError([]error{nil, err})), "unique violation in repeat")
+
+	assert.True(t, IsSerializationFailure(classifyErr(&pq.Error{Code: "40001"})), "serialization failure")
+	assert.True(t, IsNoRows(classifyErr(ErrNoRows)), "no rows")
+	assert.Equal(t, "", SQLState(errors.New("boom")), "no SQLState")
+}
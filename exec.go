@@ -1,16 +1,30 @@
 package papergres
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
 // function type for executional Command
-type execCmd func(*Result) error
+type execCmd func(context.Context, *Result) error
 
 // function type for a sqlx database command
-type dbCmd func(*sqlx.DB, *Result) error
+type dbCmd func(context.Context, executor, *Result) error
+
+// executor is the set of sqlx operations shared by *sqlx.DB and *sqlx.Tx so
+// that commands can run either on the connection pool or inside a transaction.
+type executor interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error)
+	Rebind(query string) string
+}
 
 // Exec executes an ad-hoc query against a connection.
 // This is only recommended for use if you have a weird case where you need to
@@ -29,38 +43,94 @@ func ExecNonQuery(sql string, conn Connection, args ...interface{}) *Result {
 }
 
 // execCommand is the single location that runs a command against the database (with the exception
-// of prepare statements).
-func execCommand(q *Query, cmd execCmd, logArgs ...interface{}) *Result {
+// of prepare statements). The database's hooks are called around the command
+// and transient failures are retried here according to the query's
+// RetryPolicy.
+func execCommand(q *Query, cmd execCmd, logFields ...LogField) *Result {
+	start := time.Now()
+
+	ctx, hooks, err := beforeQuery(q.context(), q)
+	var r *Result
+	if err != nil {
+		r = newErrResult(err)
+	} else {
+		var retries []string
+		r, retries = runCommand(ctx, q, cmd)
+		if len(retries) > 0 {
+			logFields = append(logFields, LogField{"retries", retries})
+		}
+	}
+
+	logQuery(ctx, q, r, start, logFields...)
+	afterQuery(ctx, hooks, q, r)
+	return r
+}
+
+// runCommand runs cmd, retrying it according to the query's RetryPolicy. It
+// returns the result along with a description of every failed attempt that
+// was retried.
+func runCommand(ctx context.Context, q *Query, cmd execCmd) (*Result, []string) {
 	r := NewResult()
+	if err := ctx.Err(); err != nil {
+		r.Err = &CanceledError{Ctx: err}
+		return r, nil
+	}
 
-	defer logQuery(q, r, time.Now(), logArgs...)
+	var retries []string
+	policy := q.retryPolicy()
+	for {
+		r.Attempts++
+		r.Err = classifyErr(cmd(ctx, r))
+		if !policy.shouldRetry(r.Attempts, r.Err, q.repeatable()) {
+			break
+		}
 
-	r.Err = cmd(r)
-	return r
+		retries = append(retries, fmt.Sprintf("Attempt %v / %v failed: %v",
+			r.Attempts, policy.MaxAttempts, r.Err))
+		if sleep(ctx, policy.backoff(r.Attempts)) != nil {
+			break
+		}
+
+		// start the next attempt from a clean result
+		attempts := r.Attempts
+		*r = *NewResult()
+		r.Attempts = attempts
+	}
+
+	if r.Err != nil && ctx.Err() != nil {
+		r.Err = &CanceledError{Ctx: ctx.Err(), Err: r.Err}
+	}
+	return r, retries
 }
 
-// execDB creates the database for a command before passing it on to the execCommand function
+// execDB resolves the executor for a command before passing it on to the
+// execCommand function
 func execDB(q *Query, dbcmd dbCmd) *Result {
-	return execCommand(q, func(r *Result) error {
-		return dbcmd(open(q.Database.ConnectionString()), r)
+	return execCommand(q, func(ctx context.Context, r *Result) error {
+		db, err := q.executor()
+		if err != nil {
+			return err
+		}
+		return dbcmd(ctx, db, r)
 	})
 }
 
 // exec sql that expects no results or expects LastInsertId and/or RowsAffected, which
 // is still basically a nonquery scripts. This is mostly inserts.
 func exec(q *Query, nonQuery bool) *Result {
-	cmd := func(db *sqlx.DB, r *Result) error {
+	cmd := func(ctx context.Context, db executor, r *Result) error {
 		meta := newMeta()
 
 		// nonquery is the easy path
 		if nonQuery {
-			res, err := db.Exec(q.SQL, q.Args...)
+			res, err := db.ExecContext(ctx, q.SQL, q.Args...)
 			if err != nil {
 				return err
 			}
-			meta.LastInsertId, err = res.LastInsertId()
-			if err != nil {
-				return err
+			// not every driver supports LastInsertId (lib/pq does not), so a
+			// failure here is reported through Result.LastInsertId.Err instead
+			if id, err := res.LastInsertId(); err == nil {
+				meta.LastInsertId = id
 			}
 			meta.RowsAffected, err = res.RowsAffected()
 			if err != nil {
@@ -75,7 +145,7 @@ func exec(q *Query, nonQuery bool) *Result {
 		// should blow up to indicate a bad script or that the user should
 		// be using Single() or Select()
 
-		err := db.Get(&meta, q.SQL, q.Args...)
+		err := db.GetContext(ctx, &meta, q.SQL, q.Args...)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,90 @@
+package papergres
+
+import "context"
+
+// Hook is called around every query a Database runs, including each
+// iteration of a Repeat and the COMMIT or ROLLBACK of a transaction. Use it
+// for auditing, tracing, metrics or rewriting queries.
+//
+// BeforeQuery is called before the query runs and returns the context to run
+// it under, which is also passed to AfterQuery. It may change q.SQL and
+// q.Args, which changes the query itself, but not for Repeat iterations since
+// their statement is prepared beforehand. If it returns an error the query is
+// not run and Result.Err is set to that error.
+//
+// AfterQuery is called once the query is done, with retries included, for
+// every hook whose BeforeQuery was called.
+type Hook interface {
+	BeforeQuery(ctx context.Context, q *Query) (context.Context, error)
+	AfterQuery(ctx context.Context, q *Query, r *Result)
+}
+
+// RepeatHook can be implemented by a Hook to also be called around a whole
+// Repeat. The context returned by BeforeRepeat is the one the statement is
+// prepared with and that is passed to the BeforeQuery of every iteration, so
+// the iterations can be tied to the Repeat they are part of. An error from
+// BeforeRepeat stops the Repeat before any iteration runs.
+type RepeatHook interface {
+	BeforeRepeat(ctx context.Context, r *Repeat) (context.Context, error)
+	AfterRepeat(ctx context.Context, r *Repeat, results []*Result, err error)
+}
+
+// AddHook adds hooks to run around every query of the database. BeforeQuery
+// is called on hooks in the order they were added and AfterQuery in reverse
+// order. Add hooks before the database is shared between goroutines.
+func (db *Database) AddHook(hooks ...Hook) *Database {
+	db.hooks = append(db.hooks, hooks...)
+	return db
+}
+
+// beforeQuery calls BeforeQuery on the database's hooks in order, stopping at
+// the first error. It returns the context to run the query with and the hooks
+// that were called.
+func beforeQuery(ctx context.Context, q *Query) (context.Context, []Hook, error) {
+	hooks := q.Database.hooks
+	for i, h := range hooks {
+		hctx, err := h.BeforeQuery(ctx, q)
+		if err != nil {
+			return ctx, hooks[:i], err
+		}
+		if hctx != nil {
+			ctx = hctx
+		}
+	}
+	return ctx, hooks, nil
+}
+
+// afterQuery calls AfterQuery on hooks in reverse order.
+func afterQuery(ctx context.Context, hooks []Hook, q *Query, r *Result) {
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].AfterQuery(ctx, q, r)
+	}
+}
+
+// beforeRepeat calls BeforeRepeat on the database's hooks that implement
+// RepeatHook, like beforeQuery.
+func beforeRepeat(ctx context.Context, r *Repeat) (context.Context, []RepeatHook, error) {
+	var called []RepeatHook
+	for _, h := range r.Query.Database.hooks {
+		rh, ok := h.(RepeatHook)
+		if !ok {
+			continue
+		}
+		hctx, err := rh.BeforeRepeat(ctx, r)
+		if err != nil {
+			return ctx, called, err
+		}
+		if hctx != nil {
+			ctx = hctx
+		}
+		called = append(called, rh)
+	}
+	return ctx, called, nil
+}
+
+// afterRepeat calls AfterRepeat on hooks in reverse order.
+func afterRepeat(ctx context.Context, hooks []RepeatHook, r *Repeat, results []*Result, err error) {
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].AfterRepeat(ctx, r, results, err)
+	}
+}
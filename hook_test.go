@@ -0,0 +1,108 @@
+package papergres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey string
+
+// recordHook records its calls in calls and can fail BeforeQuery.
+type recordHook struct {
+	name  string
+	calls *[]string
+	err   error
+}
+
+func (h recordHook) BeforeQuery(ctx context.Context, q *Query) (context.Context, error) {
+	*h.calls = append(*h.calls, "before "+h.name)
+	if h.err != nil {
+		return ctx, h.err
+	}
+	return context.WithValue(ctx, ctxKey(h.name), true), nil
+}
+
+func (h recordHook) AfterQuery(ctx context.Context, q *Query, r *Result) {
+	*h.calls = append(*h.calls, "after "+h.name)
+}
+
+// rewriteHook appends a comment to every query.
+type rewriteHook struct{}
+
+func (rewriteHook) BeforeQuery(ctx context.Context, q *Query) (context.Context, error) {
+	q.SQL += " -- audited"
+	return ctx, nil
+}
+
+func (rewriteHook) AfterQuery(ctx context.Context, q *Query, r *Result) {}
+
+func TestHooksRunAroundQueries(t *testing.T) {
+	var calls []string
+	db := NewConnection(testDbURL, "papergres_tests").NewDatabase().AddHook(
+		recordHook{name: "a", calls: &calls},
+		recordHook{name: "b", calls: &calls},
+		rewriteHook{},
+	)
+
+	q := db.Query("SELECT 1")
+	r := execCommand(q, func(ctx context.Context, r *Result) error {
+		assert.Equal(t, true, ctx.Value(ctxKey("a")), "context from hook a")
+		assert.Equal(t, true, ctx.Value(ctxKey("b")), "context from hook b")
+		assert.Equal(t, "SELECT 1 -- audited", q.SQL, "rewritten sql")
+		return nil
+	})
+
+	assert.Nil(t, r.Err)
+	assert.Equal(t, []string{"before a", "before b", "after b", "after a"}, calls)
+}
+
+func TestHookErrorStopsQuery(t *testing.T) {
+	var calls []string
+	denied := errors.New("denied")
+	db := NewConnection(testDbURL, "papergres_tests").NewDatabase().AddHook(
+		recordHook{name: "a", calls: &calls},
+		recordHook{name: "b", calls: &calls, err: denied},
+		recordHook{name: "c", calls: &calls},
+	)
+
+	ran := false
+	r := execCommand(db.Query("SELECT 1"), func(ctx context.Context, r *Result) error {
+		ran = true
+		return nil
+	})
+
+	assert.False(t, ran, "query not run")
+	assert.Equal(t, denied, r.Err)
+	assert.Equal(t, []string{"before a", "before b", "after a"}, calls)
+}
+
+func TestHookErrorStillEndsTx(t *testing.T) {
+	var calls []string
+	denied := errors.New("denied")
+	db := NewConnection(testDbURL, "papergres_tests").NewDatabase().AddHook(
+		recordHook{name: "a", calls: &calls, err: denied},
+	)
+	tx := &Tx{Database: db}
+
+	var ended []string
+	commit := func() error {
+		ended = append(ended, "commit")
+		return nil
+	}
+	rollback := func() error {
+		ended = append(ended, "rollback")
+		return nil
+	}
+
+	err := tx.end("COMMIT", commit, rollback)
+	assert.Equal(t, denied, err)
+	assert.Equal(t, []string{"rollback"}, ended, "commit rolled back")
+
+	ended = nil
+	err = tx.end("ROLLBACK", rollback, rollback)
+	assert.Equal(t, denied, err)
+	assert.Equal(t, []string{"rollback"}, ended, "rolled back")
+}
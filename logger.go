@@ -1,13 +1,23 @@
 package papergres
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
 	"time"
 )
 
 // Log is the way to log the scripting activity happening from the library to the database
 var Log Logger
 
+// StructuredLog receives leveled log entries with fields instead of the
+// preformatted messages sent to Log. When it is set Log is not used.
+var StructuredLog StructuredLogger
+
+// QueryLogLevel is the level queries that succeed are logged at. Failed and
+// slow queries are always logged at LevelWarn.
+var QueryLogLevel = LevelDebug
+
 // Logger is the required interface for the papergres logger
 type Logger interface {
 	Info(args ...interface{})
@@ -16,8 +26,58 @@ type Logger interface {
 	Debugf(format string, args ...interface{})
 }
 
+// warnLogger is implemented by Loggers that have a warn level. Warnings go to
+// Info for Loggers that don't.
+type warnLogger interface {
+	Warn(args ...interface{})
+}
+
+// StructuredLogger is the interface for leveled, structured logging. Query
+// entries have the fields sql, args, duration, rows_returned, rows_affected
+// and attempts, plus arg_names for named queries, error when the query failed
+// and repeat_index or bulk_batch when it is part of a Repeat or BulkInsert.
+type StructuredLogger interface {
+	Log(ctx context.Context, level Level, msg string, fields ...LogField)
+}
+
+// Level is the severity of a log entry.
+type Level int
+
+// Log levels, from least to most severe.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the level name, e.g. "WARN".
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// LogField is a key value pair attached to a structured log entry.
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
 // logDebug writes a 'DEBUG' level log
 func logDebug(args ...interface{}) {
+	if StructuredLog != nil {
+		StructuredLog.Log(context.Background(), LevelDebug, fmt.Sprint(args...))
+		return
+	}
 	// Return early if the logger is not initialized
 	if Log == nil {
 		return
@@ -25,20 +85,106 @@ func logDebug(args ...interface{}) {
 	Log.Debug(args)
 }
 
-// logQuery debugs out a query and a result.
-func logQuery(q *Query, res *Result, start time.Time, logArgs ...interface{}) {
+// WithSlowQueryThreshold logs every query of the database that takes longer
+// than d at warn level, with its full SQL and args, no matter the sample rate.
+// A d of 0, the default, turns the slow query log off.
+func (db *Database) WithSlowQueryThreshold(d time.Duration) *Database {
+	db.slowQuery = d
+	return db
+}
+
+// WithLogSampleRate logs only a fraction, between 0 and 1, of the database's
+// successful queries that aren't slow. Failed and slow queries are always
+// logged. The default rate of 1 logs every query, 0 logs none of them.
+func (db *Database) WithLogSampleRate(rate float64) *Database {
+	if rate < 0 {
+		rate = 0
+	} else if rate > 1 {
+		rate = 1
+	}
+	db.logSampleRate = rate
+	return db
+}
+
+// logQuery logs out a query and a result. extra holds additional fields, like
+// the repeat index, that are added after the standard query fields. ctx is
+// passed on to StructuredLog.
+func logQuery(ctx context.Context, q *Query, res *Result, start time.Time, extra ...LogField) {
 	res.ExecutionTime = time.Since(start)
 
+	if StructuredLog == nil && Log == nil {
+		return
+	}
+
+	db := q.Database
+	level := QueryLogLevel
+	switch {
+	case res.Err != nil:
+		level = LevelWarn
+	case db.slowQuery > 0 && res.ExecutionTime > db.slowQuery:
+		level = LevelWarn
+		extra = append(extra, LogField{"slow_query_threshold", db.slowQuery})
+	case db.logSampleRate < 1 && rand.Float64() >= db.logSampleRate:
+		return
+	}
+
+	if StructuredLog != nil {
+		fields := []LogField{
+			{"sql", q.SQL},
+			{"args", logArgs(q)},
+			{"duration", res.ExecutionTime},
+			{"rows_returned", res.RowsReturned},
+			{"rows_affected", res.RowsAffected.Count},
+			{"attempts", res.Attempts},
+		}
+		if len(q.names) > 0 {
+			fields = append(fields, LogField{"arg_names", q.names})
+		}
+		if res.Err != nil {
+			fields = append(fields, LogField{"error", res.Err})
+		}
+		StructuredLog.Log(ctx, level, "postgres query", append(fields, extra...)...)
+		return
+	}
+
 	// Prepare output
 	l := fmt.Sprintf("\n== POSTGRES QUERY ==%s\n== RESULT ==%s",
 		q.String(), res.String())
 
-	if len(logArgs) >= 1 {
+	if len(extra) >= 1 {
 		l += "\n== ADDITIONAL INFO ==\n"
-		for _, a := range logArgs {
-			l += fmt.Sprintf("%v\n", a)
+		for _, f := range extra {
+			l += fmt.Sprintf("%s: %v\n", f.Key, f.Value)
+		}
+	}
+
+	switch {
+	case level < LevelInfo:
+		Log.Debug(l)
+	case level == LevelInfo:
+		Log.Info(l)
+	default:
+		if w, ok := Log.(warnLogger); ok {
+			w.Warn(l)
+		} else {
+			Log.Info(l)
 		}
 	}
+}
 
-	logDebug(l)
+// logArgs returns the query's arguments as they should be logged, masked by
+// the database's ArgRedactor. It returns nil for queries that omit their args.
+func logArgs(q *Query) []interface{} {
+	if q.omitArgs {
+		return nil
+	}
+	redact := q.Database.redactArg
+	if redact == nil || len(q.Args) == 0 {
+		return q.Args
+	}
+	args := make([]interface{}, len(q.Args))
+	for i, a := range q.Args {
+		args[i] = redact(i, a)
+	}
+	return args
 }
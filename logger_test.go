@@ -0,0 +1,141 @@
+package papergres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logEntry struct {
+	level  Level
+	msg    string
+	fields map[string]interface{}
+}
+
+// captureLogger is a StructuredLogger that keeps every entry.
+type captureLogger struct {
+	entries []logEntry
+}
+
+func (c *captureLogger) Log(ctx context.Context, level Level, msg string, fields ...LogField) {
+	e := logEntry{level: level, msg: msg, fields: make(map[string]interface{})}
+	for _, f := range fields {
+		e.fields[f.Key] = f.Value
+	}
+	c.entries = append(c.entries, e)
+}
+
+// useStructuredLog sets StructuredLog for the test and restores it after.
+func useStructuredLog(t *testing.T, l StructuredLogger) {
+	prev, prevLog := StructuredLog, Log
+	StructuredLog, Log = l, nil
+	t.Cleanup(func() { StructuredLog, Log = prev, prevLog })
+}
+
+func TestLogQueryStructuredFields(t *testing.T) {
+	capture := &captureLogger{}
+	useStructuredLog(t, capture)
+
+	db := Connection{Database: "paperchain"}.NewDatabase().WithArgRedactor(RedactArgs(1))
+	q := db.Query("SELECT * FROM paper.book WHERE id = $1 AND token = $2", 7, "secret")
+
+	res := NewResult()
+	res.RowsReturned = 3
+	res.Attempts = 1
+	logQuery(context.Background(), q, res, time.Now(), LogField{"repeat_index", 2})
+
+	res = NewResult()
+	res.Err = errors.New("boom")
+	logQuery(context.Background(), q, res, time.Now())
+
+	if !assert.Len(t, capture.entries, 2, "log entries") {
+		return
+	}
+
+	ok := capture.entries[0]
+	assert.Equal(t, LevelDebug, ok.level, "successful query level")
+	assert.Equal(t, q.SQL, ok.fields["sql"], "sql")
+	assert.Equal(t, 3, ok.fields["rows_returned"], "rows returned")
+	assert.Equal(t, 2, ok.fields["repeat_index"], "extra field")
+	assert.Equal(t, []interface{}{7, redacted}, ok.fields["args"], "redacted args")
+	assert.NotContains(t, ok.fields, "error", "error field")
+
+	failed := capture.entries[1]
+	assert.Equal(t, LevelWarn, failed.level, "failed query level")
+	assert.Equal(t, res.Err, failed.fields["error"], "error field")
+}
+
+func TestLogQueryOmitsArgs(t *testing.T) {
+	capture := &captureLogger{}
+	useStructuredLog(t, capture)
+
+	db := Connection{Database: "paperchain"}.NewDatabase()
+	q := db.Query("INSERT INTO paper.book (title) VALUES ($1), ($2)", "a", "b")
+	q.omitArgs = true
+
+	logQuery(context.Background(), q, NewResult(), time.Now(), LogField{"bulk_rows", 2})
+
+	if !assert.Len(t, capture.entries, 1, "log entries") {
+		return
+	}
+	assert.Nil(t, capture.entries[0].fields["args"], "args")
+	assert.Equal(t, 2, capture.entries[0].fields["bulk_rows"], "row count")
+	assert.NotContains(t, q.String(), "Args:", "query string")
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	sl := NewSlogLogger(l)
+
+	sl.Log(context.Background(), LevelDebug, "hidden")
+	sl.Log(context.Background(), LevelWarn, "postgres query",
+		LogField{"sql", "SELECT 1"}, LogField{"rows_returned", 1})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !assert.Len(t, lines, 1, "only the warn entry") {
+		return
+	}
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &entry), "json entry")
+	assert.Equal(t, "WARN", entry["level"], "level")
+	assert.Equal(t, "postgres query", entry["msg"], "msg")
+	assert.Equal(t, "SELECT 1", entry["sql"], "sql")
+	assert.Equal(t, float64(1), entry["rows_returned"], "rows returned")
+}
+
+func TestSlowQueryAndSampling(t *testing.T) {
+	capture := &captureLogger{}
+	useStructuredLog(t, capture)
+
+	db := Connection{Database: "paperchain"}.NewDatabase().
+		WithSlowQueryThreshold(time.Millisecond).
+		WithLogSampleRate(0)
+	q := db.Query("SELECT pg_sleep(1)")
+
+	// fast successful queries are sampled out
+	logQuery(context.Background(), q, NewResult(), time.Now())
+	assert.Len(t, capture.entries, 0, "sampled out query")
+
+	// slow ones are logged at warn
+	logQuery(context.Background(), q, NewResult(), time.Now().Add(-time.Second))
+	// and so are failures
+	res := NewResult()
+	res.Err = errors.New("boom")
+	logQuery(context.Background(), q, res, time.Now())
+
+	if !assert.Len(t, capture.entries, 2, "slow and failed queries") {
+		return
+	}
+	for _, e := range capture.entries {
+		assert.Equal(t, LevelWarn, e.level, "level")
+	}
+	assert.Equal(t, time.Millisecond, capture.entries[0].fields["slow_query_threshold"], "threshold field")
+}
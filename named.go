@@ -0,0 +1,129 @@
+package papergres
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// NamedParamsFn is a function that takes in the iteration and returns the
+// destination and the named arg, a map or struct, for a named query
+// execution.
+type NamedParamsFn func(i int) (dest interface{}, arg interface{})
+
+// NamedQuery creates a query from SQL with `:name` placeholders, which are
+// bound to the fields of arg by sqlx: map keys, or struct fields by their `db`
+// tag. Use `::` for a literal colon, e.g. `created_at::date`. The names are
+// logged next to their values.
+//
+// arg may be nil to only use the query with RepeatNamed. Slices are not
+// expanded like they are by ExecAllIn, use `= ANY(:ids)` with pq.Array
+// instead.
+func (db *Database) NamedQuery(sql string, arg interface{}) (*Query, error) {
+	return newNamedQuery(db.Query(sql), arg)
+}
+
+// NamedQuery creates a query with named parameters that runs in the
+// transaction, see Database.NamedQuery.
+func (tx *Tx) NamedQuery(sql string, arg interface{}) (*Query, error) {
+	return newNamedQuery(tx.Query(sql), arg)
+}
+
+// RepeatNamed is Repeat for queries created with NamedQuery. The param
+// selector function returns the destination and the named arg of each
+// iteration, which is bound to the query's names. An iteration whose arg
+// can't be bound fails with the binding error.
+func (q *Query) RepeatNamed(times int, pSelectorFn NamedParamsFn) *Repeat {
+	r := q.Repeat(times, nil)
+	r.namedFn = pSelectorFn
+	return r
+}
+
+// newNamedQuery compiles the named SQL of q to positional placeholders and
+// binds arg.
+func newNamedQuery(q *Query, arg interface{}) (*Query, error) {
+	sql, names, err := compileNamed(q.SQL)
+	if err != nil {
+		return nil, err
+	}
+	q.named = q.SQL
+	q.SQL = sql
+	q.names = names
+
+	if arg != nil {
+		if q.Args, err = q.bindNamed(arg); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// nameRunes are the runes, along with '_' and '.', allowed in a parameter
+// name.
+var nameRunes = []*unicode.RangeTable{unicode.Letter, unicode.Digit}
+
+// bindNamed returns the positional args of a named query for arg.
+func (q *Query) bindNamed(arg interface{}) ([]interface{}, error) {
+	if q.named == "" {
+		return nil, errNotNamedQuery
+	}
+	_, args, err := sqlx.Named(q.named, arg)
+	if err != nil {
+		return nil, fmt.Errorf("binding named args: %w", err)
+	}
+	return args, nil
+}
+
+// compileNamed replaces the `:name` placeholders of sql with $1, $2, etc. and
+// returns the name of each placeholder in order. It follows the rules of
+// sqlx's named query compiler so the names line up with the args bound by
+// sqlx.Named.
+func compileNamed(sql string) (string, []string, error) {
+	qs := []byte(sql)
+	rebound := make([]byte, 0, len(qs))
+	var names []string
+
+	inName := false
+	last := len(qs) - 1
+	name := make([]byte, 0, 10)
+
+	isNameRune := func(b byte) bool {
+		return unicode.IsOneOf(nameRunes, rune(b))
+	}
+
+	for i, b := range qs {
+		switch {
+		case b == ':' && inName && i > 0 && qs[i-1] == ':':
+			// the second ':' of a '::' escape
+			rebound = append(rebound, ':')
+			inName = false
+		case b == ':' && inName:
+			return "", nil, fmt.Errorf("unexpected `:` while reading named param at %d", i)
+		case b == ':':
+			inName = true
+			name = name[:0]
+		case inName && i > 0 && b == '=' && len(name) == 0:
+			rebound = append(rebound, ':', '=')
+			inName = false
+		case inName && (isNameRune(b) || b == '_' || b == '.') && i != last:
+			name = append(name, b)
+		case inName:
+			inName = false
+			if i == last && isNameRune(b) {
+				name = append(name, b)
+			}
+			names = append(names, string(name))
+			rebound = append(rebound, '$')
+			rebound = strconv.AppendInt(rebound, int64(len(names)), 10)
+			if i != last || !isNameRune(b) {
+				rebound = append(rebound, b)
+			}
+		default:
+			rebound = append(rebound, b)
+		}
+	}
+
+	return string(rebound), names, nil
+}
@@ -0,0 +1,58 @@
+package papergres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileNamedMatchesSqlx(t *testing.T) {
+	arg := map[string]interface{}{"id": 1, "title": "Dune", "a.b": 2, "x1": 3}
+	for _, sql := range []string{
+		"SELECT * FROM paper.book WHERE book_id = :id",
+		"UPDATE paper.book SET title = :title WHERE book_id = :id AND book_id = :id;",
+		"SELECT created_at::date FROM paper.book WHERE title = :title",
+		"SELECT :a.b, :x1",
+		"SELECT x := 1, :id",
+	} {
+		compiled, names, err := compileNamed(sql)
+		assert.Nil(t, err, sql)
+
+		expected, args, err := sqlx.Named(sql, arg)
+		assert.Nil(t, err, sql)
+		assert.Equal(t, sqlx.Rebind(sqlx.DOLLAR, expected), compiled, sql)
+		assert.Equal(t, len(args), len(names), sql)
+		for i, n := range names {
+			assert.Equal(t, arg[n], args[i], sql)
+		}
+	}
+
+	_, _, err := compileNamed("SELECT :a:b")
+	assert.NotNil(t, err, "colon inside a name")
+}
+
+func TestNamedQuery(t *testing.T) {
+	db := NewConnection(testDbURL, "papergres_tests").NewDatabase()
+
+	book := Book{BookID: 6, Title: "The Martian", Author: "Andy Weir"}
+	q, err := db.NamedQuery("UPDATE paper.book SET title = :title, author = :author WHERE book_id = :book_id", book)
+	assert.Nil(t, err, "NamedQuery")
+	assert.Equal(t, "UPDATE paper.book SET title = $1, author = $2 WHERE book_id = $3", q.SQL)
+	assert.Equal(t, []interface{}{"The Martian", "Andy Weir", PrimaryKey(6)}, q.Args)
+
+	s := q.String()
+	assert.True(t, strings.Contains(s, "$1 (:title): The Martian"), s)
+	assert.True(t, strings.Contains(s, "$3 (:book_id): 6"), s)
+
+	_, err = db.NamedQuery("SELECT * FROM paper.book WHERE title = :missing", book)
+	assert.NotNil(t, err, "missing name")
+
+	q, err = db.NamedQuery("SELECT * FROM paper.book WHERE title = :title", nil)
+	assert.Nil(t, err, "no arg")
+	assert.Nil(t, q.Args, "no args bound")
+
+	_, err = db.Query("SELECT 1").bindNamed(book)
+	assert.Equal(t, errNotNamedQuery, err, "positional query")
+}
@@ -0,0 +1,24 @@
+module github.com/Paperchain/papergres/otelpapergres
+
+go 1.21
+
+require (
+	github.com/Paperchain/papergres v0.0.0-20261016095832-a6ada0071ad5
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/sdk v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/jmoiron/sqlx v1.3.4 // indirect
+	github.com/lib/pq v1.10.3 // indirect
+	go.opentelemetry.io/otel/metric v1.28.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+)
+
+// Builds inside this repository use the papergres checkout next to the
+// module; projects importing it get the version required above.
+replace github.com/Paperchain/papergres => ../
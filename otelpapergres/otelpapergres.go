@@ -0,0 +1,163 @@
+// Package otelpapergres traces papergres queries with OpenTelemetry.
+//
+// Add the hook to a database and every query gets a client span, as a child
+// of the span in the query's context:
+//
+//	db := conn.NewDatabase().AddHook(otelpapergres.NewHook())
+//	res := db.Query(sql, args...).WithContext(ctx).ExecAll(&rows)
+//
+// Each Repeat gets a span of its own with a child span per iteration.
+package otelpapergres
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Paperchain/papergres"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by this package.
+const instrumentationName = "github.com/Paperchain/papergres/otelpapergres"
+
+// Span attribute keys.
+const (
+	DBSystemKey     = attribute.Key("db.system")
+	DBStatementKey  = attribute.Key("db.statement")
+	DBNameKey       = attribute.Key("db.name")
+	DBOperationKey  = attribute.Key("db.operation")
+	RowsReturnedKey = attribute.Key("db.papergres.rows_returned")
+	RowsAffectedKey = attribute.Key("db.papergres.rows_affected")
+	AttemptsKey     = attribute.Key("db.papergres.attempts")
+	SQLStateKey     = attribute.Key("db.papergres.sqlstate")
+	RepeatCountKey  = attribute.Key("db.papergres.repeat_count")
+)
+
+// Hook is a papergres.Hook and papergres.RepeatHook that traces queries.
+type Hook struct {
+	tracer   trace.Tracer
+	omitStmt bool
+}
+
+// Option configures a Hook.
+type Option func(*hookConfig)
+
+// hookConfig holds the options a Hook is created with.
+type hookConfig struct {
+	provider trace.TracerProvider
+	omitStmt bool
+}
+
+// WithTracerProvider sets the provider spans are created with. It defaults to
+// the global provider, otel.GetTracerProvider().
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *hookConfig) {
+		c.provider = tp
+	}
+}
+
+// WithoutStatement leaves db.statement off the spans, for queries whose SQL
+// should not leave the process.
+func WithoutStatement() Option {
+	return func(c *hookConfig) {
+		c.omitStmt = true
+	}
+}
+
+// NewHook creates a tracing hook.
+func NewHook(opts ...Option) *Hook {
+	c := hookConfig{}
+	for _, o := range opts {
+		o(&c)
+	}
+	if c.provider == nil {
+		c.provider = otel.GetTracerProvider()
+	}
+	return &Hook{
+		tracer:   c.provider.Tracer(instrumentationName),
+		omitStmt: c.omitStmt,
+	}
+}
+
+// BeforeQuery starts a client span for the query.
+func (h *Hook) BeforeQuery(ctx context.Context, q *papergres.Query) (context.Context, error) {
+	ctx, _ = h.tracer.Start(ctx, spanName(q), trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(h.queryAttributes(q)...))
+	return ctx, nil
+}
+
+// AfterQuery records the result on the query's span and ends it.
+func (h *Hook) AfterQuery(ctx context.Context, q *papergres.Query, r *papergres.Result) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		RowsReturnedKey.Int(r.RowsReturned),
+		RowsAffectedKey.Int64(r.RowsAffected.Count),
+		AttemptsKey.Int(r.Attempts),
+	)
+	setError(span, r.Err)
+	span.End()
+}
+
+// BeforeRepeat starts the span the iterations of the Repeat are children of.
+func (h *Hook) BeforeRepeat(ctx context.Context, r *papergres.Repeat) (context.Context, error) {
+	attrs := append(h.queryAttributes(r.Query), RepeatCountKey.Int(r.N))
+	ctx, _ = h.tracer.Start(ctx, "REPEAT "+spanName(r.Query),
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx, nil
+}
+
+// AfterRepeat ends the Repeat's span.
+func (h *Hook) AfterRepeat(ctx context.Context, r *papergres.Repeat, results []*papergres.Result, err error) {
+	span := trace.SpanFromContext(ctx)
+	setError(span, err)
+	span.End()
+}
+
+// queryAttributes returns the attributes every span of q starts with.
+func (h *Hook) queryAttributes(q *papergres.Query) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		DBSystemKey.String("postgresql"),
+		DBNameKey.String(q.Database.Connection().Database),
+	}
+	if op := operation(q.SQL); op != "" {
+		attrs = append(attrs, DBOperationKey.String(op))
+	}
+	if !h.omitStmt {
+		attrs = append(attrs, DBStatementKey.String(q.SQL))
+	}
+	return attrs
+}
+
+// setError marks span as failed with err, if there is one.
+func setError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	if code := papergres.SQLState(err); code != "" {
+		span.SetAttributes(SQLStateKey.String(code))
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// spanName names a query's span after its operation and database, e.g.
+// "SELECT paperchain".
+func spanName(q *papergres.Query) string {
+	name := strings.TrimSpace(operation(q.SQL) + " " + q.Database.Connection().Database)
+	if name == "" {
+		return "postgres"
+	}
+	return name
+}
+
+// operation returns the first keyword of a SQL statement in upper case.
+func operation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimRight(fields[0], ";"))
+}
@@ -0,0 +1,108 @@
+package otelpapergres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Paperchain/papergres"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// unreachableURL refuses connections right away so queries fail without a
+// running server.
+const unreachableURL = "postgres://papergres@127.0.0.1:1/paperchain?sslmode=disable&connect_timeout=1"
+
+func newTracedDatabase(opts ...Option) (*papergres.Database, *tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	opts = append(opts, WithTracerProvider(tp))
+	db := papergres.NewConnection(unreachableURL, "otelpapergres-tests").NewDatabase().
+		AddHook(NewHook(opts...))
+	return db, sr, tp
+}
+
+func attrs(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range s.Attributes() {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestQuerySpan(t *testing.T) {
+	db, sr, tp := newTracedDatabase()
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	var ids []int
+	res := db.Query("SELECT id FROM paper.book").WithContext(ctx).ExecAll(&ids)
+	parent.End()
+
+	if res.Err == nil {
+		t.Fatal("expected query against unreachable server to fail")
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected query and parent span, got %d", len(spans))
+	}
+	s := spans[0]
+	if s.Name() != "SELECT paperchain" {
+		t.Errorf("unexpected span name %q", s.Name())
+	}
+	if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("query span is not a child of the context's span")
+	}
+	a := attrs(s)
+	if a[DBSystemKey].AsString() != "postgresql" || a[DBNameKey].AsString() != "paperchain" ||
+		a[DBStatementKey].AsString() != "SELECT id FROM paper.book" || a[AttemptsKey].AsInt64() != 1 {
+		t.Errorf("unexpected attributes %v", a)
+	}
+	if s.Status().Code != codes.Error || len(s.Events()) == 0 {
+		t.Errorf("expected error status and event, got %v", s.Status())
+	}
+}
+
+func TestWithoutStatement(t *testing.T) {
+	db, sr, _ := newTracedDatabase(WithoutStatement())
+	db.Query("DELETE FROM paper.book").ExecNonQuery()
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	a := attrs(spans[0])
+	if _, ok := a[DBStatementKey]; ok {
+		t.Errorf("statement should be omitted, got %v", a)
+	}
+	if a[DBOperationKey].AsString() != "DELETE" {
+		t.Errorf("expected operation, got %v", a)
+	}
+}
+
+func TestRepeatSpan(t *testing.T) {
+	db, sr, _ := newTracedDatabase()
+
+	params := func(i int) (interface{}, []interface{}) {
+		var id int
+		return &id, []interface{}{i}
+	}
+	_, err := db.Query("SELECT $1::int").Repeat(3, params).Exec()
+	if err == nil {
+		t.Fatal("expected repeat against unreachable server to fail")
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected the repeat span, got %d", len(spans))
+	}
+	s := spans[0]
+	if s.Name() != "REPEAT SELECT paperchain" || attrs(s)[RepeatCountKey].AsInt64() != 3 {
+		t.Errorf("unexpected repeat span %q %v", s.Name(), attrs(s))
+	}
+	if s.Status().Code != codes.Error {
+		t.Errorf("expected error status, got %v", s.Status())
+	}
+}
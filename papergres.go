@@ -1,26 +1,34 @@
 package papergres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"log"
+	"fmt"
 
-	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq" // install postgres driver
 )
 
 var (
-	errNoDriver        = errors.New("no database driver loaded")
-	errMultipleDrivers = errors.New("more than 1 data driver loaded")
-	errUnableToOpenDB  = errors.New("unable to open sql database")
+	errUnknownDriver  = errors.New("database driver not registered")
+	errUnableToOpenDB = errors.New("unable to open sql database")
+	errNoPrimaryKey   = errors.New("no primary key field, tag one with `db_pk:\"true\"`")
+	errUnknownService = errors.New("connection service not found")
+	errNotNamedQuery  = errors.New("query was not created with NamedQuery")
+	errMultipleHosts  = errors.New("the postgres driver connects to a single host")
 
-	sqlDriver string
+	errNoConflictColumns = errors.New("upsert requires at least one conflict column")
+	errNoUpdateColumns   = errors.New("upsert has no columns to update")
 
 	// sql DBs are meant to stay open indefinitely so we cache them here
-	// by connection string. The DB internally manages the connection pool.
-	openDBs map[string]*sqlx.DB
+	// by driver and connection string. The DB internally manages the
+	// connection pool.
+	openDBs = newRegistry()
 )
 
+// defaultDriver is the lib/pq driver, registered by importing it above.
+const defaultDriver = "postgres"
+
 // the first thing to get called
 func init() {
 	Reset()
@@ -31,49 +39,61 @@ func init() {
 // Can be used to reset DB instances if one were to unexpectedly fail which I'm
 // not sure is possible.
 func Reset() {
-	Shutdown()
-	sqlDriver = ""
-	openDBs = make(map[string]*sqlx.DB)
+	if err := Shutdown(context.Background()); err != nil {
+		logDebug(err)
+	}
 }
 
-// Shutdown performs a graceful shutdown of all DBs
-func Shutdown() {
-	for _, db := range openDBs {
-		if err := db.Close(); err != nil {
-			log.Fatalf("Error shutting down DB: %s", err.Error())
+// Shutdown performs a graceful shutdown of all DBs. Every pool is closed even
+// if closing another one fails, and all failures are returned together. If
+// ctx is done before all pools are closed Shutdown returns ctx.Err() while
+// the remaining pools finish closing in the background.
+func Shutdown(ctx context.Context) error {
+	dbs := openDBs.removeAll()
+
+	done := make(chan error, 1)
+	go func() {
+		var errs []error
+		for _, db := range dbs {
+			if err := db.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("error shutting down DB: %w", err))
+			}
 		}
+		done <- errors.Join(errs...)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-// open returns a new open connection to DB and adds it to connection pool.
-func open(conn string) *sqlx.DB {
-	if db, ok := openDBs[conn]; ok {
-		return db
-	}
+// OpenPools returns the connection of every pool that is currently open.
+func OpenPools() []Connection {
+	return openDBs.connections()
+}
 
-	db, err := sqlx.Open(getDriver(), conn)
-	if err != nil {
-		logDebug(err, conn)
-		panic(errUnableToOpenDB)
-	}
-	openDBs[conn] = db
-	return db
+// AllPoolStats returns the stats of every pool that is currently open, in the
+// same order as OpenPools. Unlike Database.Stats it never opens a pool.
+func AllPoolStats() []PoolStats {
+	return openDBs.stats()
 }
 
-// getDriver returns a registered driver to connect to db
-func getDriver() string {
-	if sqlDriver != "" {
-		return sqlDriver
+// getDriver returns the registered driver to connect to db with. An empty
+// name returns the default postgres driver.
+func getDriver(name string) (string, error) {
+	if name == "" {
+		name = defaultDriver
 	}
 
-	drivers := sql.Drivers()
-	if len(drivers) == 0 {
-		panic(errNoDriver)
-	} else if len(drivers) > 1 {
-		logDebug(drivers)
-		panic(errMultipleDrivers)
+	for _, d := range sql.Drivers() {
+		if d == name {
+			return name, nil
+		}
 	}
 
-	sqlDriver = drivers[0]
-	return sqlDriver
+	logDebug(sql.Drivers())
+	return "", fmt.Errorf("%w: %s", errUnknownDriver, name)
 }
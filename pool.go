@@ -0,0 +1,85 @@
+package papergres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PoolConfig tunes the connection pool that is opened for a Connection.
+// Zero values keep the database/sql defaults.
+type PoolConfig struct {
+	// MaxOpenConns is the maximum number of open connections, e.g. to stay
+	// under a PgBouncer pool size.
+	MaxOpenConns int
+
+	// MaxIdleConns is the maximum number of idle connections kept around.
+	// Set it negative to keep no idle connections.
+	MaxIdleConns int
+
+	// ConnMaxLifetime is the maximum time a connection may be reused.
+	ConnMaxLifetime time.Duration
+
+	// ConnMaxIdleTime is the maximum time a connection may sit idle.
+	ConnMaxIdleTime time.Duration
+}
+
+// apply sets the non-zero settings on db.
+func (p PoolConfig) apply(db *sqlx.DB) {
+	if p.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(p.MaxOpenConns)
+	}
+	if p.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(p.MaxIdleConns)
+	}
+	if p.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	}
+	if p.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+	}
+}
+
+// PoolStats describes the state of a database's connection pool.
+type PoolStats struct {
+	// Database and AppName identify the connection the pool belongs to.
+	Database string
+	AppName  string
+
+	// MaxOpenConnections is the configured limit, 0 means unlimited.
+	MaxOpenConnections int
+
+	// OpenConnections is the number of open connections, both InUse and Idle.
+	OpenConnections int
+	InUse           int
+	Idle            int
+
+	// WaitCount is the total number of times a query had to wait for a free
+	// connection, and WaitDuration the total time spent waiting.
+	WaitCount    int64
+	WaitDuration time.Duration
+
+	// Connections closed because of MaxIdleConns, ConnMaxIdleTime and
+	// ConnMaxLifetime respectively.
+	MaxIdleClosed     int64
+	MaxIdleTimeClosed int64
+	MaxLifetimeClosed int64
+}
+
+// newPoolStats creates PoolStats for conn from database/sql stats.
+func newPoolStats(conn *Connection, s sql.DBStats) PoolStats {
+	return PoolStats{
+		Database:           conn.Database,
+		AppName:            conn.AppName,
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration,
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
@@ -0,0 +1,25 @@
+module github.com/Paperchain/papergres/prompapergres
+
+go 1.21
+
+require (
+	github.com/Paperchain/papergres v0.0.0-20261016095832-a6ada0071ad5
+	github.com/prometheus/client_golang v1.19.1
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/jmoiron/sqlx v1.3.4 // indirect
+	github.com/lib/pq v1.10.3 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
+
+// Builds inside this repository use the papergres checkout next to the
+// module; projects importing it get the version required above.
+replace github.com/Paperchain/papergres => ../
@@ -0,0 +1,218 @@
+// Package prompapergres exports papergres query and connection pool metrics to
+// Prometheus.
+//
+// The Collector is a papergres.Hook that records every query of the databases
+// it is added to, and a prometheus.Collector that reports those along with
+// the stats of every open pool:
+//
+//	c := prompapergres.NewCollector()
+//	prometheus.MustRegister(c)
+//	db := conn.NewDatabase().AddHook(c)
+//
+// Metrics are labeled with the app and database of the query's Connection,
+// from Connection.AppName and Connection.Database.
+package prompapergres
+
+import (
+	"context"
+
+	"github.com/Paperchain/papergres"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// namespace prefixes every metric name.
+const namespace = "papergres"
+
+// poolLabels are the labels of the pool metrics, and the first labels of
+// every query metric.
+var poolLabels = []string{"app", "database"}
+
+// Collector records query metrics as a papergres.Hook and exposes them, along
+// with pool metrics, as a prometheus.Collector.
+type Collector struct {
+	duration     *prometheus.HistogramVec
+	errors       *prometheus.CounterVec
+	rowsReturned *prometheus.CounterVec
+	rowsAffected *prometheus.CounterVec
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+// Option configures a Collector.
+type Option func(*collectorConfig)
+
+// collectorConfig holds the options a Collector is created with.
+type collectorConfig struct {
+	buckets []float64
+}
+
+// WithBuckets sets the buckets, in seconds, of the query duration histogram.
+// It defaults to prometheus.DefBuckets.
+func WithBuckets(buckets []float64) Option {
+	return func(c *collectorConfig) {
+		c.buckets = buckets
+	}
+}
+
+// NewCollector creates a Collector. Register it with a prometheus.Registerer
+// and add it as a hook to the databases to record.
+func NewCollector(opts ...Option) *Collector {
+	c := collectorConfig{buckets: prometheus.DefBuckets}
+	for _, o := range opts {
+		o(&c)
+	}
+
+	poolDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", name), help, poolLabels, nil)
+	}
+
+	return &Collector{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "query_duration_seconds",
+			Help:      "Time taken to execute queries, retries included.",
+			Buckets:   c.buckets,
+		}, poolLabels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "query_errors_total",
+			Help:      "Number of failed queries by SQLSTATE, none when the error did not come from postgres.",
+		}, append(poolLabels, "sqlstate")),
+		rowsReturned: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "query_rows_returned_total",
+			Help:      "Number of rows returned by queries.",
+		}, poolLabels),
+		rowsAffected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "query_rows_affected_total",
+			Help:      "Number of rows affected by queries.",
+		}, poolLabels),
+
+		maxOpen:           poolDesc("max_open_connections", "Maximum number of open connections, 0 is unlimited."),
+		open:              poolDesc("open_connections", "Number of open connections, in use and idle."),
+		inUse:             poolDesc("in_use_connections", "Number of connections in use."),
+		idle:              poolDesc("idle_connections", "Number of idle connections."),
+		waitCount:         poolDesc("wait_count_total", "Number of times a query waited for a connection."),
+		waitDuration:      poolDesc("wait_duration_seconds_total", "Time spent waiting for a connection."),
+		maxIdleClosed:     poolDesc("max_idle_closed_total", "Connections closed because of MaxIdleConns."),
+		maxIdleTimeClosed: poolDesc("max_idle_time_closed_total", "Connections closed because of ConnMaxIdleTime."),
+		maxLifetimeClosed: poolDesc("max_lifetime_closed_total", "Connections closed because of ConnMaxLifetime."),
+	}
+}
+
+// BeforeQuery does nothing, queries are recorded once they are done.
+func (c *Collector) BeforeQuery(ctx context.Context, q *papergres.Query) (context.Context, error) {
+	return ctx, nil
+}
+
+// AfterQuery records the query's result.
+func (c *Collector) AfterQuery(ctx context.Context, q *papergres.Query, r *papergres.Result) {
+	conn := q.Database.Connection()
+	app, database := conn.AppName, conn.Database
+
+	c.duration.WithLabelValues(app, database).Observe(r.ExecutionTime.Seconds())
+	if r.RowsReturned > 0 {
+		c.rowsReturned.WithLabelValues(app, database).Add(float64(r.RowsReturned))
+	}
+	if r.RowsAffected.Count > 0 {
+		c.rowsAffected.WithLabelValues(app, database).Add(float64(r.RowsAffected.Count))
+	}
+	if r.Err != nil {
+		code := papergres.SQLState(r.Err)
+		if code == "" {
+			code = "none"
+		}
+		c.errors.WithLabelValues(app, database, code).Inc()
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.duration.Describe(ch)
+	c.errors.Describe(ch)
+	c.rowsReturned.Describe(ch)
+	c.rowsAffected.Describe(ch)
+
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+}
+
+// Collect implements prometheus.Collector. Pool metrics are read from every
+// open pool at collection time, pools with the same app and database are
+// added up.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.duration.Collect(ch)
+	c.errors.Collect(ch)
+	c.rowsReturned.Collect(ch)
+	c.rowsAffected.Collect(ch)
+
+	for _, s := range poolStats() {
+		labels := []string{s.AppName, s.Database}
+		gauge := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v, labels...)
+		}
+		counter := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v, labels...)
+		}
+
+		gauge(c.maxOpen, float64(s.MaxOpenConnections))
+		gauge(c.open, float64(s.OpenConnections))
+		gauge(c.inUse, float64(s.InUse))
+		gauge(c.idle, float64(s.Idle))
+		counter(c.waitCount, float64(s.WaitCount))
+		counter(c.waitDuration, s.WaitDuration.Seconds())
+		counter(c.maxIdleClosed, float64(s.MaxIdleClosed))
+		counter(c.maxIdleTimeClosed, float64(s.MaxIdleTimeClosed))
+		counter(c.maxLifetimeClosed, float64(s.MaxLifetimeClosed))
+	}
+}
+
+// poolStats returns the stats of the open pools, added up by app and
+// database so no two share the same labels.
+func poolStats() []papergres.PoolStats {
+	type key struct{ app, database string }
+	var order []key
+	sums := make(map[key]*papergres.PoolStats)
+
+	for _, s := range papergres.AllPoolStats() {
+		k := key{s.AppName, s.Database}
+		sum, ok := sums[k]
+		if !ok {
+			s := s
+			sums[k] = &s
+			order = append(order, k)
+			continue
+		}
+		sum.MaxOpenConnections += s.MaxOpenConnections
+		sum.OpenConnections += s.OpenConnections
+		sum.InUse += s.InUse
+		sum.Idle += s.Idle
+		sum.WaitCount += s.WaitCount
+		sum.WaitDuration += s.WaitDuration
+		sum.MaxIdleClosed += s.MaxIdleClosed
+		sum.MaxIdleTimeClosed += s.MaxIdleTimeClosed
+		sum.MaxLifetimeClosed += s.MaxLifetimeClosed
+	}
+
+	stats := make([]papergres.PoolStats, len(order))
+	for i, k := range order {
+		stats[i] = *sums[k]
+	}
+	return stats
+}
@@ -0,0 +1,77 @@
+package prompapergres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Paperchain/papergres"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// unreachableURL refuses connections right away so queries fail without a
+// running server.
+const unreachableURL = "postgres://papergres@127.0.0.1:1/paperchain?sslmode=disable&connect_timeout=1"
+
+func TestCollectorRecordsQueries(t *testing.T) {
+	c := NewCollector(WithBuckets([]float64{0.1, 1}))
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+
+	db := papergres.NewConnection(unreachableURL, "prompapergres-tests").NewDatabase().AddHook(c)
+	defer db.Close()
+
+	var ids []int
+	if res := db.Query("SELECT id FROM paper.book").ExecAll(&ids); res.Err == nil {
+		t.Fatal("expected query against unreachable server to fail")
+	}
+
+	if n := testutil.ToFloat64(c.errors.WithLabelValues("prompapergres-tests", "paperchain", "none")); n != 1 {
+		t.Errorf("expected 1 error, got %v", n)
+	}
+
+	expected := `
+# HELP papergres_pool_max_open_connections Maximum number of open connections, 0 is unlimited.
+# TYPE papergres_pool_max_open_connections gauge
+papergres_pool_max_open_connections{app="prompapergres-tests",database="paperchain"} 0
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "papergres_pool_max_open_connections")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if n, err := testutil.GatherAndCount(reg, "papergres_query_duration_seconds"); err != nil || n != 1 {
+		t.Errorf("expected 1 duration histogram, got %v %v", n, err)
+	}
+}
+
+func TestPoolStatsAreAddedUp(t *testing.T) {
+	for _, url := range []string{unreachableURL, unreachableURL + "&statement_timeout=1000"} {
+		db := papergres.NewConnection(url, "prompapergres-sum").NewDatabase().
+			WithPool(papergres.PoolConfig{MaxOpenConns: 2})
+		if err := db.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+	}
+
+	var found int
+	for _, s := range poolStats() {
+		if s.AppName == "prompapergres-sum" {
+			found++
+			if s.MaxOpenConnections != 4 {
+				t.Errorf("expected pools to be added up, got %v", s.MaxOpenConnections)
+			}
+		}
+	}
+	if found != 1 {
+		t.Errorf("expected a single entry for the app, got %d", found)
+	}
+
+	// the registry rejects duplicate label sets
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(NewCollector())
+	if _, err := reg.Gather(); err != nil {
+		t.Fatal(err)
+	}
+}
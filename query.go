@@ -1,8 +1,10 @@
 package papergres
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -13,12 +15,59 @@ type Query struct {
 	Database *Database
 	Args     []interface{}
 	insert   bool
+
+	// tx is set when the query was created from a Tx and must run on it.
+	tx *Tx
+
+	// ctx is the context the query runs under. nil means context.Background.
+	ctx context.Context
+
+	// retry overrides the database's retry policy for this query.
+	retry *RetryPolicy
+
+	// noRetry disables retries for statements that can't be run again, like
+	// a COPY whose rows have already been read.
+	noRetry bool
+
+	// omitArgs keeps the arguments out of the logs, for bulk insert batches
+	// whose thousands of values are too many to be useful.
+	omitArgs bool
+
+	// named is the original SQL of a query created with NamedQuery and names
+	// the parameter name of each positional arg.
+	named string
+	names []string
 }
 
 // SelectParamsFn is a function that takes in the iteration and
 // returns the destination and args for a SQL execution.
 type SelectParamsFn func(i int) (dest interface{}, args []interface{})
 
+// WithContext returns a shallow copy of the query that runs under ctx. When ctx
+// is canceled or its deadline passes the running statement is interrupted and
+// Result.Err is set to a *CanceledError.
+func (q *Query) WithContext(ctx context.Context) *Query {
+	if ctx == nil {
+		panic("nil context")
+	}
+	q2 := *q
+	q2.ctx = ctx
+	return &q2
+}
+
+// Context returns the query's context. It defaults to context.Background.
+func (q *Query) Context() context.Context {
+	return q.context()
+}
+
+// context returns the query's context, falling back to context.Background.
+func (q *Query) context() context.Context {
+	if q.ctx != nil {
+		return q.ctx
+	}
+	return context.Background()
+}
+
 // Exec runs a sql command given a connection and expects LastInsertId or RowsAffected
 // to be returned by the script. Use this for INSERTs
 func (q *Query) Exec() *Result {
@@ -28,8 +77,11 @@ func (q *Query) Exec() *Result {
 // ExecAll gets many rows and populates the given slice
 // dest should be a pointer to a slice
 func (q *Query) ExecAll(dest interface{}) *Result {
-	all := func(db *sqlx.DB, r *Result) error {
-		err := db.Select(dest, q.SQL, q.Args...)
+	n := getLen(dest)
+	all := func(ctx context.Context, db executor, r *Result) error {
+		// drop rows scanned by a failed attempt before a retry
+		truncateSlice(dest, n)
+		err := db.SelectContext(ctx, dest, q.SQL, q.Args...)
 
 		r.RowsReturned = getLen(dest)
 
@@ -45,7 +97,11 @@ func (q *Query) ExecAll(dest interface{}) *Result {
 // the query then has to be rebinded to change default bindvar to target bindvar
 // like `$1` (dollar sign followed by a number) for postgres etc.
 func (q *Query) ExecAllIn(dest interface{}) *Result {
-	all := func(db *sqlx.DB, r *Result) error {
+	n := getLen(dest)
+	all := func(ctx context.Context, db executor, r *Result) error {
+		// drop rows scanned by a failed attempt before a retry
+		truncateSlice(dest, n)
+
 		query, args, err := sqlx.In(q.SQL, q.Args...)
 		if err != nil {
 			return err
@@ -55,7 +111,7 @@ func (q *Query) ExecAllIn(dest interface{}) *Result {
 		query = db.Rebind(query)
 
 		// Execute a select query using this DB
-		err = db.Select(dest, query, args...)
+		err = db.SelectContext(ctx, dest, query, args...)
 
 		r.RowsReturned = getLen(dest)
 
@@ -74,8 +130,8 @@ func (q *Query) ExecNonQuery() *Result {
 // If more than 1 row is returned it takes the first one.
 // Expects at least 1 row or it will return an error.
 func (q *Query) ExecSingle(dest interface{}) *Result {
-	single := func(db *sqlx.DB, r *Result) error {
-		err := db.Get(dest, q.SQL, q.Args...)
+	single := func(ctx context.Context, db executor, r *Result) error {
+		err := db.GetContext(ctx, dest, q.SQL, q.Args...)
 		if err == nil {
 			r.RowsReturned = 1
 		}
@@ -90,9 +146,9 @@ func (q *Query) ExecSingle(dest interface{}) *Result {
 // index. Make sure to use pointers to ensure the sql results fill your structs.
 // Use this when you want to run the same query for many different parameters,
 // like getting data for child entities for a collection of parents.
-// This function executes the iterations concurrently so each loop should not
-// rely on state from a previous loops execution. The function should be
-// extremely fast and efficient with DB resources.
+// Iterations can be executed concurrently with Repeat.Concurrency so each loop
+// should not rely on state from a previous loops execution. The function should
+// be extremely fast and efficient with DB resources.
 // Returned error will contain all errors that occurred in any iterations.
 //
 // Example usage:
@@ -106,14 +162,24 @@ func (q *Query) ExecSingle(dest interface{}) *Result {
 //
 func (q *Query) Repeat(times int, pSelectorFn SelectParamsFn) *Repeat {
 	return &Repeat{
-		q,
-		pSelectorFn,
-		times,
+		Query:    q,
+		ParamsFn: pSelectorFn,
+		N:        times,
 	}
 }
 
+// executor returns the transaction the query belongs to or, when there is
+// none, the pooled DB for the query's connection.
+func (q *Query) executor() (executor, error) {
+	if q.tx != nil {
+		return q.tx.tx, nil
+	}
+	return q.Database.pool()
+}
+
 // String returns a SQL query and it's arguments along with connection info in a
-// pretty format.
+// pretty format. Connection secrets are masked, as are the arguments selected
+// by the database's ArgRedactor.
 func (q *Query) String() string {
 	return fmt.Sprintf(`
 	Query:
@@ -121,22 +187,28 @@ func (q *Query) String() string {
 	%s
 	Connection: %s
 	`,
-		q.SQL, argsToString(q.Args), prettifyConnString(q.Database.ConnectionString()))
+		q.SQL, argsToString(logArgs(q), q.names), prettifyConnString(q.Database.ConnectionString()))
 }
 
 // argsToString iterates over each argument and returns them in a neatly
-// formatted string.
-func argsToString(args []interface{}) string {
-	s := ""
-	if len(args) > 0 {
-		s = "Args:"
+// formatted string. Args of named queries are shown with their names.
+func argsToString(args []interface{}, names []string) string {
+	if len(args) == 0 {
+		return ""
 	}
 
+	var b strings.Builder
+	b.WriteString("Args:")
+
 	// The idea here is to keep adding each argument on a separate line
 	for i, a := range args {
-		s += fmt.Sprintf("\n\t$%v: %v", i+1, a)
+		if i < len(names) {
+			fmt.Fprintf(&b, "\n\t$%v (:%s): %v", i+1, names[i], a)
+			continue
+		}
+		fmt.Fprintf(&b, "\n\t$%v: %v", i+1, a)
 	}
-	return s
+	return b.String()
 }
 
 // getLen returns the number of items in a slice. It can be used to populate
@@ -0,0 +1,69 @@
+package papergres
+
+// redacted replaces secrets wherever papergres renders a connection or
+// redacted query argument.
+const redacted = "xxxxx"
+
+// sensitiveParams are the connection parameters masked when rendered.
+var sensitiveParams = map[string]bool{
+	"password":    true,
+	"sslpassword": true,
+	"sslkey":      true,
+}
+
+// ArgRedactor masks sensitive query arguments before they are logged. It is
+// called with the position (starting at 0) and value of every argument and
+// returns the value to print in its place. It never changes what is sent to
+// the database.
+type ArgRedactor func(i int, arg interface{}) interface{}
+
+// RedactArgs returns an ArgRedactor that masks the arguments at the given
+// positions, starting at 0. Use it for queries with a known layout, e.g.
+// RedactArgs(1) for `UPDATE account SET token = $2 WHERE id = $1`.
+func RedactArgs(positions ...int) ArgRedactor {
+	mask := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		mask[p] = true
+	}
+	return func(i int, arg interface{}) interface{} {
+		if mask[i] {
+			return redacted
+		}
+		return arg
+	}
+}
+
+// WithArgRedactor sets the function used to mask query arguments in logs and
+// Query.String for every query of the database. By default arguments are
+// printed as is.
+func (db *Database) WithArgRedactor(fn ArgRedactor) *Database {
+	db.redactArg = fn
+	return db
+}
+
+// Redacted returns a copy of the connection with the password and SSL key
+// masked, safe to log or print.
+func (conn Connection) Redacted() Connection {
+	if conn.Password != "" {
+		conn.Password = redacted
+	}
+	if conn.SSLKey != "" {
+		conn.SSLKey = redacted
+	}
+	if len(conn.Params) > 0 {
+		params := make(map[string]string, len(conn.Params))
+		for k, v := range conn.Params {
+			params[k] = redactParam(k, v)
+		}
+		conn.Params = params
+	}
+	return conn
+}
+
+// redactParam masks the value of a sensitive connection parameter.
+func redactParam(key, value string) string {
+	if sensitiveParams[key] && value != "" {
+		return redacted
+	}
+	return value
+}
@@ -0,0 +1,49 @@
+package papergres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryStringRedactsSecrets(t *testing.T) {
+	conn := Connection{
+		Database: "paperchain",
+		User:     "paper",
+		Password: "hunter2",
+		Host:     "localhost",
+		SSLKey:   "/etc/ssl/private/client.key",
+		Params:   map[string]string{"sslpassword": "keypass"},
+	}
+	db := conn.NewDatabase().WithArgRedactor(RedactArgs(1))
+	s := db.Query("UPDATE account SET token = $2 WHERE id = $1", 7, "tok-secret").String()
+
+	assert.NotContains(t, s, "hunter2", "password")
+	assert.NotContains(t, s, "client.key", "ssl key")
+	assert.NotContains(t, s, "keypass", "ssl password")
+	assert.NotContains(t, s, "tok-secret", "redacted arg")
+
+	assert.Contains(t, s, "user=paper", "user")
+	assert.Contains(t, s, "password="+redacted, "redacted password")
+	assert.Contains(t, s, "$1: 7", "plain arg")
+	assert.Contains(t, s, "$2: "+redacted, "redacted arg")
+}
+
+func TestConnectionRedacted(t *testing.T) {
+	conn := Connection{
+		Password: "hunter2",
+		SSLKey:   "/client.key",
+		Params:   map[string]string{"sslpassword": "keypass", "options": "-c geqo=off"},
+	}
+	r := conn.Redacted()
+	assert.Equal(t, redacted, r.Password, "password")
+	assert.Equal(t, redacted, r.SSLKey, "ssl key")
+	assert.Equal(t, redacted, r.Params["sslpassword"], "ssl password")
+	assert.Equal(t, "-c geqo=off", r.Params["options"], "non secret param")
+
+	assert.Equal(t, "hunter2", conn.Password, "original password")
+	assert.Equal(t, "keypass", conn.Params["sslpassword"], "original params")
+
+	assert.NotContains(t, prettifyConnString("password='unterminated"), "unterminated",
+		"unparsable connection string")
+}
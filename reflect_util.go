@@ -11,6 +11,9 @@ import (
 // GetTypeName gets the type name of an object
 func getTypeName(v interface{}) string {
 	t := reflect.TypeOf(v)
+	if t == nil {
+		return ""
+	}
 	switch t.Kind() {
 	case reflect.Ptr:
 		return t.Elem().Name()
@@ -46,6 +49,18 @@ func convertToSlice(v interface{}) ([]interface{}, error) {
 	return s, nil
 }
 
+// truncateSlice shrinks the slice pointed to by v to n items. It does nothing
+// if v is not a pointer to a slice.
+func truncateSlice(v interface{}, n int) {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
+		return
+	}
+	if s := val.Elem(); s.Len() > n {
+		s.SetLen(n)
+	}
+}
+
 // Field is a struct field that represents a single entity of an object.
 // To set a field as primary add `db_pk:true` to tag.
 type Field struct {
@@ -0,0 +1,136 @@
+package papergres
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// dbKey identifies a cached DB.
+type dbKey struct {
+	driver string
+	dsn    string
+}
+
+// pool is a cached DB along with the connection it was opened for.
+type pool struct {
+	conn Connection
+	db   *sqlx.DB
+}
+
+// registry is a concurrency safe cache of open DBs.
+type registry struct {
+	mu    sync.Mutex
+	pools map[dbKey]*pool
+}
+
+// newRegistry creates an empty registry.
+func newRegistry() *registry {
+	return &registry{pools: make(map[dbKey]*pool)}
+}
+
+// open returns the cached DB for key, opening it if there is none. The lock
+// is held while opening so concurrent first queries against the same DSN
+// share a single DB.
+func (r *registry) open(key dbKey, conn Connection) (*sqlx.DB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p, ok := r.pools[key]; ok {
+		return p.db, nil
+	}
+
+	db, err := sqlx.Open(key.driver, key.dsn)
+	if err != nil {
+		logDebug(err, prettifyConnString(key.dsn))
+		return nil, fmt.Errorf("%w: %v", errUnableToOpenDB, err)
+	}
+	conn.Pool.apply(db)
+	r.pools[key] = &pool{conn: conn, db: db}
+	return db, nil
+}
+
+// get returns the cached DB for key, or nil if it is not open.
+func (r *registry) get(key dbKey) *sqlx.DB {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p, ok := r.pools[key]; ok {
+		return p.db
+	}
+	return nil
+}
+
+// remove takes the DB for key out of the registry and returns it, or nil if
+// there is none.
+func (r *registry) remove(key dbKey) *sqlx.DB {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.pools[key]
+	if !ok {
+		return nil
+	}
+	delete(r.pools, key)
+	return p.db
+}
+
+// removeAll empties the registry and returns every DB that was in it.
+func (r *registry) removeAll() []*sqlx.DB {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dbs := make([]*sqlx.DB, 0, len(r.pools))
+	for _, p := range r.pools {
+		dbs = append(dbs, p.db)
+	}
+	r.pools = make(map[dbKey]*pool)
+	return dbs
+}
+
+// connections returns the connection of every cached DB, ordered by driver
+// and DSN.
+func (r *registry) connections() []Connection {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := r.sortedKeys()
+	conns := make([]Connection, len(keys))
+	for i, k := range keys {
+		conns[i] = r.pools[k].conn
+	}
+	return conns
+}
+
+// stats returns the pool stats of every cached DB, in the same order as
+// connections.
+func (r *registry) stats() []PoolStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := r.sortedKeys()
+	stats := make([]PoolStats, len(keys))
+	for i, k := range keys {
+		p := r.pools[k]
+		stats[i] = newPoolStats(&p.conn, p.db.Stats())
+	}
+	return stats
+}
+
+// sortedKeys returns the keys of the cached DBs ordered by driver and DSN.
+// r.mu must be held.
+func (r *registry) sortedKeys() []dbKey {
+	keys := make([]dbKey, 0, len(r.pools))
+	for k := range r.pools {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].driver != keys[j].driver {
+			return keys[i].driver < keys[j].driver
+		}
+		return keys[i].dsn < keys[j].dsn
+	})
+	return keys
+}
@@ -0,0 +1,73 @@
+package papergres
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryIsSafeForConcurrentOpen(t *testing.T) {
+	assert.Nil(t, Shutdown(context.Background()), "shutdown")
+
+	db := NewConnection(testDbURL, "papergres_registry_test").NewDatabase()
+	other := NewConnection(testDbURL, "papergres_registry_other").NewDatabase()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, db.Open(), "open")
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, other.Open(), "open other")
+		}()
+	}
+	wg.Wait()
+
+	pools := OpenPools()
+	assert.Equal(t, 2, len(pools), "one pool per DSN")
+
+	assert.Nil(t, db.Close(), "close")
+	pools = OpenPools()
+	assert.Equal(t, 1, len(pools), "closed pool removed")
+	assert.Equal(t, "papergres_registry_other", pools[0].AppName, "remaining pool")
+
+	assert.Nil(t, Shutdown(context.Background()), "shutdown")
+	assert.Equal(t, 0, len(OpenPools()), "all pools closed")
+}
+
+func TestPoolConfigIsApplied(t *testing.T) {
+	db := NewConnection(testDbURL, "papergres_pool_test").NewDatabase().
+		WithPool(PoolConfig{MaxOpenConns: 7})
+	assert.Nil(t, db.Open(), "open")
+	defer db.Close()
+
+	stats := db.Stats()
+	assert.Equal(t, 7, stats.MaxOpenConnections, "max open")
+	assert.Equal(t, "paperchain", stats.Database, "database")
+	assert.Equal(t, "papergres_pool_test", stats.AppName, "app name")
+
+	db.WithPool(PoolConfig{MaxOpenConns: 3})
+	assert.Equal(t, 3, db.Stats().MaxOpenConnections, "applied to open pool")
+}
+
+func TestAllPoolStats(t *testing.T) {
+	db := NewConnection(testDbURL, "papergres_all_stats_test").NewDatabase().
+		WithPool(PoolConfig{MaxOpenConns: 5})
+	assert.Nil(t, db.Open(), "open")
+	defer db.Close()
+
+	var found bool
+	for _, s := range AllPoolStats() {
+		if s.AppName == "papergres_all_stats_test" {
+			found = true
+			assert.Equal(t, 5, s.MaxOpenConnections, "max open")
+		}
+	}
+	assert.True(t, found, "open pool reported")
+	assert.Equal(t, len(OpenPools()), len(AllPoolStats()), "one stats per pool")
+}
@@ -1,10 +1,11 @@
 package papergres
 
 import (
-	"errors"
-	"fmt"
-	"strings"
+	"context"
 	"sync"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
 )
 
 // Repeat holds the iteration count and params function
@@ -13,81 +14,188 @@ type Repeat struct {
 	Query    *Query
 	ParamsFn SelectParamsFn
 	N        int
+
+	// workers is the number of iterations executed at the same time.
+	workers int
+
+	// stopOnErr stops starting new iterations once one has failed.
+	stopOnErr bool
+
+	// namedFn replaces ParamsFn for repeats created with RepeatNamed.
+	namedFn NamedParamsFn
+}
+
+// Concurrency sets how many iterations are executed at the same time by a pool
+// of n workers. The default of 1 runs the iterations one after another.
+//
+// n is capped at the pool's max open connections when one is set so the
+// workers don't starve each other (or the rest of the application) of
+// connections. Inside a transaction iterations always run one at a time since
+// a transaction is bound to a single connection.
+//
+// ParamsFn is called from the workers, so with n > 1 it must be safe for
+// concurrent use.
+func (r *Repeat) Concurrency(n int) *Repeat {
+	r.workers = n
+	return r
+}
+
+// StopOnError controls what happens when an iteration fails. By default every
+// iteration runs regardless of failures. With stop set, no new iterations are
+// started after the first failure; iterations already running are allowed to
+// finish and the ones never started get a Result with ErrRepeatSkipped, which
+// is also reported for their index in the returned *RepeatError.
+func (r *Repeat) StopOnError(stop bool) *Repeat {
+	r.stopOnErr = stop
+	return r
 }
 
 // Exec executes the repeat query command. Internally this will prepare the
-// statement with the database and then create go routines to execute each
-// statement.
+// statement with the database and then execute each iteration on a pool of
+// workers sized by Concurrency. results[i] always holds the Result of
+// iteration i. If any iteration fails the returned error is a *RepeatError
+// listing the failed indexes.
 func (r *Repeat) Exec() ([]*Result, error) {
-	// Open connection to db
-	db := open(r.Query.Database.ConnectionString())
-	stmt, err := db.Preparex(r.Query.SQL)
+	ctx, hooks, err := beforeRepeat(r.Query.context(), r)
+	if err != nil {
+		afterRepeat(ctx, hooks, r, nil, err)
+		return nil, err
+	}
+
+	results, err := r.exec(ctx)
+	afterRepeat(ctx, hooks, r, results, err)
+	return results, err
+}
+
+// exec prepares the statement under ctx and executes the iterations.
+func (r *Repeat) exec(ctx context.Context) ([]*Result, error) {
+	// Use the pooled db, or the transaction if the query belongs to one
+	db, err := r.Query.executor()
 	if err != nil {
 		return nil, err
 	}
+	stmt, err := db.PreparexContext(ctx, r.Query.SQL)
+	if err != nil {
+		return nil, classifyErr(err)
+	}
 	defer stmt.Close()
 
-	// Set up and exec work in go routines managed by a semaphore
 	results := make([]*Result, r.N)
 
-	// Create a slice to capture errors for each iteration.
-	errs := []error{}
+	// Each iteration only ever writes its own index, so no locking is needed.
+	errs := make([]error, r.N)
+
+	// Set when an iteration fails and we should stop handing out work.
+	var failed int32
 
-	// Set up a wait group to ensure proper synchronization
+	indexes := make(chan int)
 	wg := sync.WaitGroup{}
 
-	for i := 0; i < r.N; i++ {
+	for w := 0; w < r.concurrency(); w++ {
 		wg.Add(1)
-
-		func(i int) {
-			// We can replace the above call with a go routine here
-			// go func(i int){}
-			// but that can cause an error - "too many clients open"
-			// so to throttle go routine creation we can experiment and try to
-			// spin go routines in batches of n<10.
+		go func() {
 			defer wg.Done()
-
-			dest, args := r.ParamsFn(i)
-			// create a new query for each iteration since the args change
-			qs := r.Query.Database.Query(r.Query.SQL, args...)
-
-			cmd := func(result *Result) error {
-				if r.Query.insert {
-					meta := newMeta()
-					err := stmt.Get(&meta, qs.Args...)
-					result.setMeta(meta)
-					return err
+			for i := range indexes {
+				// another worker may have failed while we waited for i
+				if r.stopOnErr && atomic.LoadInt32(&failed) == 1 {
+					continue
+				}
+				results[i] = r.iterate(ctx, stmt, i)
+				if results[i].Err != nil {
+					errs[i] = results[i].Err
+					atomic.StoreInt32(&failed, 1)
 				}
-				err := stmt.Select(dest, qs.Args...)
-				result.RowsReturned = getLen(dest)
-				return err
 			}
+		}()
+	}
 
-			// fire away
-			result := execCommand(qs, cmd, fmt.Sprintf("Repeat Index: %v / %v", i+1, r.N))
-			results[i] = result
-			if result.Err != nil {
-				errs = append(errs, result.Err)
-			}
-		}(i)
+	for i := 0; i < r.N; i++ {
+		if r.stopOnErr && atomic.LoadInt32(&failed) == 1 {
+			break
+		}
+		indexes <- i
 	}
+	close(indexes)
 	wg.Wait()
 
-	return results, mergeErrs(errs)
+	for i, res := range results {
+		if res == nil {
+			results[i] = newErrResult(ErrRepeatSkipped)
+			errs[i] = ErrRepeatSkipped
+		}
+	}
+
+	return results, newRepeatError(errs)
+}
+
+// iterate executes iteration i of the repeat with the prepared statement
+// under ctx.
+func (r *Repeat) iterate(ctx context.Context, stmt *sqlx.Stmt, i int) *Result {
+	dest, args, bindErr := r.params(i)
+	// copy the query for each iteration since the args change
+	qs := *r.Query
+	qs.Args = args
+	qs.ctx = ctx
+	n := getLen(dest)
+
+	cmd := func(ctx context.Context, result *Result) error {
+		if bindErr != nil {
+			return bindErr
+		}
+		if r.Query.insert {
+			meta := newMeta()
+			err := stmt.GetContext(ctx, &meta, qs.Args...)
+			result.setMeta(meta)
+			return err
+		}
+		// drop rows scanned by a failed attempt before a retry
+		truncateSlice(dest, n)
+		err := stmt.SelectContext(ctx, dest, qs.Args...)
+		result.RowsReturned = getLen(dest)
+		return err
+	}
+
+	// fire away
+	return execCommand(&qs, cmd, LogField{"repeat_index", i}, LogField{"repeat_count", r.N})
+}
+
+// params returns the destination and args of iteration i.
+func (r *Repeat) params(i int) (dest interface{}, args []interface{}, err error) {
+	if r.namedFn == nil {
+		dest, args = r.ParamsFn(i)
+		return dest, args, nil
+	}
+	dest, arg := r.namedFn(i)
+	args, err = r.Query.bindNamed(arg)
+	return dest, args, err
+}
+
+// concurrency returns the number of workers to execute the iterations with.
+func (r *Repeat) concurrency() int {
+	n := r.workers
+	if n < 1 || r.Query.tx != nil {
+		return 1
+	}
+	if n > r.N {
+		n = r.N
+	}
+	if limit := r.Query.Database.Stats().MaxOpenConnections; limit > 0 && n > limit {
+		n = limit
+	}
+	return n
 }
 
-// mergeErrs returns a compilation of all the errors for each execution of
-// repeat. Each error is on it's own separate line.
-func mergeErrs(errs []error) error {
-	var s string
-	for _, e := range errs {
+// newRepeatError collects the non-nil errors of errs, indexed by their
+// position, into a *RepeatError. It returns nil if there are none.
+func newRepeatError(errs []error) error {
+	var re RepeatError
+	for i, e := range errs {
 		if e != nil {
-			s += fmt.Sprintln(e)
+			re.Errors = append(re.Errors, IndexedError{Index: i, Err: e})
 		}
 	}
-	s = strings.TrimRight(s, "\n")
-	if s != "" {
-		return errors.New(s)
+	if len(re.Errors) == 0 {
+		return nil
 	}
-	return nil
+	return &re
 }
@@ -14,6 +14,10 @@ type Result struct {
 	RowsReturned  int
 	ExecutionTime time.Duration
 	Err           error
+
+	// Attempts is the number of times the query was run, which is more than
+	// 1 when transient failures were retried.
+	Attempts int
 }
 
 // PrimaryKey is the type used for primary keys
@@ -55,6 +59,14 @@ func NewResult() *Result {
 	return result
 }
 
+// newErrResult returns a Result for a command that failed before it could be
+// run against the database.
+func newErrResult(err error) *Result {
+	r := NewResult()
+	r.Err = err
+	return r
+}
+
 // String method returns query execution results in a pretty format
 func (r *Result) String() string {
 	if r == nil {
@@ -77,10 +89,11 @@ LastInsertId:  %v
 RowsAffected:  %v
 RowsReturned:  %v
 ExecutionTime: %v
+Attempts:      %v
 Error: %v
 `,
 		lid, ra, r.RowsReturned,
-		r.ExecutionTime, r.Err)
+		r.ExecutionTime, r.Attempts, r.Err)
 }
 
 // setMeta populates query execution results and errors
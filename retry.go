@@ -0,0 +1,169 @@
+package papergres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultRetryableCodes are the SQLSTATEs retried by a RetryPolicy that
+// doesn't set its own: serialization failures, deadlocks, connection
+// exceptions and server shutdowns.
+var DefaultRetryableCodes = []string{
+	"40001", // serialization_failure
+	"40P01", // deadlock_detected
+	"08000", // connection_exception
+	"08003", // connection_does_not_exist
+	"08006", // connection_failure
+	"57P01", // admin_shutdown
+	"57P03", // cannot_connect_now
+}
+
+// RetryPolicy describes how queries that fail with a transient error are
+// retried. Attach it to every query of a Database with
+// Database.WithRetryPolicy or to a single query with Query.WithRetryPolicy.
+//
+// Statements inside a transaction are never retried on their own because
+// postgres aborts the transaction on the first error. Instead Database.InTx
+// retries the whole transaction function.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry. Every following
+	// wait doubles, up to MaxBackoff.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between attempts. Zero means no cap.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction, between 0 and 1, of each wait that is
+	// randomized so that clients failing together don't retry together.
+	Jitter float64
+
+	// RetryableCodes are the SQLSTATEs to retry. nil uses
+	// DefaultRetryableCodes. Connections that broke before the statement was
+	// sent are always retried. Connections that broke while it ran are only
+	// retried for SELECT statements, since a write may already have been
+	// committed.
+	RetryableCodes []string
+}
+
+// DefaultRetryPolicy returns a policy of 3 attempts with a jittered backoff
+// starting at 50ms.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Jitter:         0.5,
+	}
+}
+
+// WithRetryPolicy sets the retry policy for every query of the database.
+func (db *Database) WithRetryPolicy(p RetryPolicy) *Database {
+	db.retry = &p
+	return db
+}
+
+// WithRetryPolicy returns a shallow copy of the query that retries transient
+// failures according to p, overriding the database's policy.
+func (q *Query) WithRetryPolicy(p RetryPolicy) *Query {
+	q2 := *q
+	q2.retry = &p
+	return &q2
+}
+
+// retryPolicy returns the policy the query runs with, or nil if it must not
+// be retried.
+func (q *Query) retryPolicy() *RetryPolicy {
+	if q.tx != nil || q.noRetry {
+		return nil
+	}
+	if q.retry != nil {
+		return q.retry
+	}
+	return q.Database.retry
+}
+
+// shouldRetry reports whether another attempt should follow a failed one.
+// repeatable tells whether the statement is safe to run again when it is
+// unknown if the failed attempt ran it.
+func (p *RetryPolicy) shouldRetry(attempt int, err error, repeatable bool) bool {
+	if p == nil || attempt >= p.MaxAttempts || IsCanceled(err) {
+		return false
+	}
+	return p.retryable(err, repeatable)
+}
+
+// retryable reports whether err is a transient failure under the policy.
+func (p *RetryPolicy) retryable(err error, repeatable bool) bool {
+	// the connection broke before the statement was sent
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	// the connection broke while the statement may have been running
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return repeatable
+	}
+
+	code := SQLState(err)
+	if code == "" {
+		return false
+	}
+	codes := p.RetryableCodes
+	if codes == nil {
+		codes = DefaultRetryableCodes
+	}
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// repeatable reports whether q only reads, so it is safe to run again after
+// failing in a way that leaves it unknown whether it ran.
+func (q *Query) repeatable() bool {
+	sql := strings.TrimLeft(q.SQL, " \t\r\n(")
+	return len(sql) >= 6 && strings.EqualFold(sql[:6], "SELECT")
+}
+
+// backoff returns how long to wait after the given failed attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 && d > 0 {
+		d -= time.Duration(rand.Int63n(int64(float64(d)*p.Jitter) + 1))
+	}
+	return d
+}
+
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,105 @@
+package papergres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	p := DefaultRetryPolicy()
+
+	assert.True(t, p.shouldRetry(1, classifyErr(&pq.Error{Code: "40001"}), false), "serialization failure")
+	assert.True(t, p.shouldRetry(2, classifyErr(&pq.Error{Code: "40P01"}), false), "deadlock")
+	assert.True(t, p.shouldRetry(1, driver.ErrBadConn, false), "bad connection")
+	assert.False(t, p.shouldRetry(3, driver.ErrBadConn, false), "out of attempts")
+	assert.False(t, p.shouldRetry(1, classifyErr(&pq.Error{Code: "23505"}), false), "unique violation")
+	assert.False(t, p.shouldRetry(1, &CanceledError{Ctx: context.Canceled, Err: driver.ErrBadConn}, false), "canceled")
+
+	p.RetryableCodes = []string{"23505"}
+	assert.True(t, p.shouldRetry(1, classifyErr(&pq.Error{Code: "23505"}), false), "custom code")
+
+	var none *RetryPolicy
+	assert.False(t, none.shouldRetry(1, driver.ErrBadConn, true), "no policy")
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     50 * time.Millisecond,
+	}
+	assert.Equal(t, 10*time.Millisecond, p.backoff(1), "first")
+	assert.Equal(t, 20*time.Millisecond, p.backoff(2), "second")
+	assert.Equal(t, 40*time.Millisecond, p.backoff(3), "third")
+	assert.Equal(t, 50*time.Millisecond, p.backoff(8), "capped")
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.backoff(2)
+		assert.True(t, d >= 10*time.Millisecond && d <= 20*time.Millisecond, "jittered")
+	}
+}
+
+func TestExecCommandRetries(t *testing.T) {
+	db := NewConnection(testDbURL, "papergres_tests").NewDatabase().
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3})
+
+	calls := 0
+	r := execCommand(db.Query("SELECT 1"), func(ctx context.Context, r *Result) error {
+		calls++
+		if calls < 3 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	})
+	assert.Nil(t, r.Err, "retried until success")
+	assert.Equal(t, 3, r.Attempts, "attempts")
+
+	calls = 0
+	permanent := errors.New("not transient")
+	r = execCommand(db.Query("SELECT 1"), func(ctx context.Context, r *Result) error {
+		calls++
+		return permanent
+	})
+	assert.Equal(t, permanent, r.Err, "not retried")
+	assert.Equal(t, 1, calls, "single call")
+}
+
+func TestRetryOnlyRepeatsSafeStatements(t *testing.T) {
+	p := DefaultRetryPolicy()
+
+	assert.True(t, p.shouldRetry(1, syscall.ECONNREFUSED, false), "never sent")
+	assert.True(t, p.shouldRetry(1, io.ErrUnexpectedEOF, true), "select on dropped connection")
+	assert.False(t, p.shouldRetry(1, io.ErrUnexpectedEOF, false), "write on dropped connection")
+	assert.False(t, p.shouldRetry(1, syscall.ECONNRESET, false), "write on reset connection")
+
+	db := NewConnection(testDbURL, "papergres_tests").NewDatabase()
+	assert.True(t, db.Query("  select * FROM paper.book").repeatable(), "select")
+	assert.True(t, db.Query("(SELECT 1) UNION (SELECT 2)").repeatable(), "parenthesized select")
+	assert.False(t, db.Query("INSERT INTO paper.book DEFAULT VALUES").repeatable(), "insert")
+	assert.False(t, db.Query("WITH ins AS (INSERT INTO paper.book DEFAULT VALUES) SELECT 1").repeatable(), "writing cte")
+}
+
+func TestExecCommandNeverRetriesCopy(t *testing.T) {
+	db := NewConnection(testDbURL, "papergres_tests").NewDatabase().
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3})
+
+	q := db.Query("COPY paper.book (title) FROM STDIN")
+	q.noRetry = true
+
+	calls := 0
+	r := execCommand(q, func(ctx context.Context, r *Result) error {
+		calls++
+		return &pq.Error{Code: "40P01"}
+	})
+	assert.NotNil(t, r.Err, "deadlock reported")
+	assert.Equal(t, 1, calls, "copy not retried")
+}
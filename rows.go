@@ -0,0 +1,199 @@
+package papergres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrStopIteration can be returned by the function passed to Iterate to stop
+// reading rows early without failing the query.
+var ErrStopIteration = errors.New("stop iteration")
+
+// Rows is a cursor over the rows of a query that reads them one at a time
+// from the database instead of loading them all into a slice:
+//
+//	rows := db.Query("SELECT * FROM paper.character").WithContext(ctx).Rows()
+//	defer rows.Close()
+//	for rows.Next() {
+//		var c Character
+//		if err := rows.StructScan(&c); err != nil {
+//			return err
+//		}
+//		// ...
+//	}
+//	res := rows.Close()
+//
+// The query is run when Rows is called and, since rows may already have been
+// read when it fails, it is never retried. A failure to run the query is
+// reported by Err and by the Result of Close. The query is logged and its
+// hooks' AfterQuery called on Close, which must always be called.
+type Rows struct {
+	q     *Query
+	ctx   context.Context
+	hooks []Hook
+	start time.Time
+
+	rows   *sqlx.Rows
+	result *Result
+	closed bool
+}
+
+// Rows runs the query and returns a cursor over its rows.
+func (q *Query) Rows() *Rows {
+	rs := &Rows{
+		q:      q,
+		start:  time.Now(),
+		result: NewResult(),
+	}
+
+	ctx, hooks, err := beforeQuery(q.context(), q)
+	rs.ctx, rs.hooks = ctx, hooks
+	if err != nil {
+		rs.result.Err = err
+		return rs
+	}
+	if err := ctx.Err(); err != nil {
+		rs.result.Err = &CanceledError{Ctx: err}
+		return rs
+	}
+
+	rs.result.Attempts = 1
+	db, err := q.executor()
+	if err != nil {
+		rs.result.Err = err
+		return rs
+	}
+	rs.rows, err = db.QueryxContext(ctx, q.SQL, q.Args...)
+	rs.fail(err)
+	return rs
+}
+
+// Next prepares the next row for reading with Scan or StructScan. It returns
+// false when there are no more rows, or on failure, which is reported by
+// Err.
+func (rs *Rows) Next() bool {
+	if rs.closed || rs.rows == nil || rs.result.Err != nil {
+		return false
+	}
+	if !rs.rows.Next() {
+		rs.fail(rs.rows.Err())
+		return false
+	}
+	rs.result.RowsReturned++
+	return true
+}
+
+// Scan copies the columns of the current row into dest, see sql.Rows.Scan.
+func (rs *Rows) Scan(dest ...interface{}) error {
+	if rs.rows == nil {
+		return rs.Err()
+	}
+	err := rs.rows.Scan(dest...)
+	rs.fail(err)
+	return err
+}
+
+// StructScan copies the current row into the struct dest points to, mapping
+// columns to fields the same way ExecAll does.
+func (rs *Rows) StructScan(dest interface{}) error {
+	if rs.rows == nil {
+		return rs.Err()
+	}
+	err := rs.rows.StructScan(dest)
+	rs.fail(err)
+	return err
+}
+
+// Err returns the error, if any, that stopped the rows from being read.
+func (rs *Rows) Err() error {
+	return rs.result.Err
+}
+
+// Close closes the cursor and returns the Result of the query, with the
+// number of rows read as RowsReturned. It is safe to call more than once and
+// always returns the same Result.
+func (rs *Rows) Close() *Result {
+	if rs.closed {
+		return rs.result
+	}
+	rs.closed = true
+
+	if rs.rows != nil {
+		rs.fail(rs.rows.Close())
+	}
+	if rs.result.Err != nil && rs.ctx.Err() != nil && !IsCanceled(rs.result.Err) {
+		rs.result.Err = &CanceledError{Ctx: rs.ctx.Err(), Err: rs.result.Err}
+	}
+
+	logQuery(rs.ctx, rs.q, rs.result, rs.start)
+	afterQuery(rs.ctx, rs.hooks, rs.q, rs.result)
+	return rs.result
+}
+
+// fail records err as the rows' error unless there already is one.
+func (rs *Rows) fail(err error) {
+	if err != nil && rs.result.Err == nil {
+		rs.result.Err = classifyErr(err)
+	}
+}
+
+// Iterate runs q and calls fn with every row, read one at a time from the
+// database, instead of loading them all into a slice like ExecAll. Rows are
+// scanned into a new T for every call, a struct mapped the same way ExecAll
+// does or a single column value like int or string.
+//
+// Iteration stops at the first error returned by fn, which is set as
+// Result.Err, unless it is ErrStopIteration which stops without an error. It
+// also stops when the query's context is done. Result.RowsReturned is the
+// number of rows read. Like Rows, the query is never retried.
+func Iterate[T any](q *Query, fn func(row *T) error) *Result {
+	rows := q.Rows()
+	structScan := isStructRow(reflect.TypeOf((*T)(nil)).Elem())
+
+	for rows.Next() {
+		row := new(T)
+
+		var err error
+		if structScan {
+			err = rows.StructScan(row)
+		} else {
+			err = rows.Scan(row)
+		}
+		if err != nil {
+			break
+		}
+
+		if err := fn(row); err != nil {
+			if !errors.Is(err, ErrStopIteration) {
+				rows.fail(err)
+			}
+			break
+		}
+	}
+
+	return rows.Close()
+}
+
+// scannerType is the type of sql.Scanner.
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// isStructRow reports whether rows should be scanned into t field by field,
+// rather than t being the value of a single column. Like sqlx, structs that
+// implement sql.Scanner or have no exported fields, e.g. time.Time, are
+// single column values.
+func isStructRow(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || reflect.PointerTo(t).Implements(scannerType) {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package papergres
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsStructRow(t *testing.T) {
+	assert.True(t, isStructRow(reflect.TypeOf(Character{})), "model struct")
+	assert.False(t, isStructRow(reflect.TypeOf(time.Time{})), "no exported fields")
+	assert.False(t, isStructRow(reflect.TypeOf(sql.NullString{})), "scanner")
+	assert.False(t, isStructRow(reflect.TypeOf(0)), "int")
+}
+
+func TestRowsReportFailureOnClose(t *testing.T) {
+	var calls []string
+	db := NewConnection("postgres://papergres@127.0.0.1:1/paperchain?sslmode=disable", "papergres_tests").
+		NewDatabase().AddHook(recordHook{name: "a", calls: &calls})
+
+	rows := db.Query("SELECT * FROM paper.character").Rows()
+	assert.False(t, rows.Next(), "no rows")
+	assert.NotNil(t, rows.Err(), "query failed")
+
+	res := rows.Close()
+	assert.Equal(t, rows.Err(), res.Err, "close result")
+	assert.Equal(t, res, rows.Close(), "close twice")
+	assert.Equal(t, []string{"before a", "after a"}, calls, "hooks called once")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res = Iterate(db.Query("SELECT * FROM paper.character").WithContext(ctx), func(c *Character) error {
+		t.Fatal("no rows expected")
+		return nil
+	})
+	assert.True(t, IsCanceled(res.Err), "canceled")
+	assert.Equal(t, 0, res.RowsReturned, "rows returned")
+}
@@ -1,6 +1,7 @@
 package papergres
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -11,6 +12,23 @@ import (
 type Schema struct {
 	Name     string
 	Database *Database
+
+	// tx is set when the schema was created from a Tx.
+	tx *Tx
+
+	// ctx is the context the schema's queries run under.
+	ctx context.Context
+}
+
+// WithContext returns a shallow copy of the schema whose queries run under
+// ctx, like Query.WithContext.
+func (s *Schema) WithContext(ctx context.Context) *Schema {
+	if ctx == nil {
+		panic("nil context")
+	}
+	s2 := *s
+	s2.ctx = ctx
+	return &s2
 }
 
 // GenerateInsert generates the insert query for the given object
@@ -35,7 +53,7 @@ func (s *Schema) GenerateInsertWithPK(obj interface{}) *Query {
 func (s *Schema) Insert(obj interface{}) *Result {
 	sql := insertSQL(obj, s.Name, false)
 	args := insertArgs(obj, false)
-	return s.Database.Query(sql, args...).Exec()
+	return s.query(sql, args...).Exec()
 }
 
 // InsertWithPK performs inserts on objects and persists the Primary key value
@@ -48,7 +66,7 @@ func (s *Schema) Insert(obj interface{}) *Result {
 func (s *Schema) InsertWithPK(obj interface{}) *Result {
 	sql := insertSQL(obj, s.Name, true)
 	args := insertArgs(obj, true)
-	return s.Database.Query(sql, args...).ExecNonQuery()
+	return s.query(sql, args...).ExecNonQuery()
 }
 
 // InsertAll inserts a slice of objects concurrently.
@@ -58,6 +76,8 @@ func (s *Schema) InsertWithPK(obj interface{}) *Result {
 // 	for i, r := range results {
 //		objs[i].Id = r.LastInsertId.ID
 //	}
+//
+// If any insert fails the error is a *RepeatError holding the failed indexes.
 func (s *Schema) InsertAll(objs interface{}) ([]*Result, error) {
 	slice, err := convertToSlice(objs)
 	if err != nil {
@@ -75,28 +95,102 @@ func (s *Schema) InsertAll(objs interface{}) ([]*Result, error) {
 		}).Exec()
 }
 
+// query creates a new query against the schema's database, or against its
+// transaction when the schema was created from a Tx.
+func (s *Schema) query(sql string, args ...interface{}) *Query {
+	var q *Query
+	if s.tx != nil {
+		q = s.tx.Query(sql, args...)
+	} else {
+		q = s.Database.Query(sql, args...)
+	}
+	q.ctx = s.ctx
+	return q
+}
+
+// GenerateUpdate generates an update query for the given object. Every column
+// other than the primary key is set from the object and the row is matched on
+// the primary key, which must be tagged with `db_pk:"true"`.
+func (s *Schema) GenerateUpdate(obj interface{}) (*Query, error) {
+	sql, err := updateSQL(obj, s.Name)
+	if err != nil {
+		return nil, err
+	}
+	return s.query(sql, updateArgs(obj)...), nil
+}
+
+// Update updates the row matching the passed in object's primary key.
+// Result.RowsAffected holds the number of updated rows, so a count of 0 means
+// no row had that primary key.
+func (s *Schema) Update(obj interface{}) *Result {
+	q, err := s.GenerateUpdate(obj)
+	if err != nil {
+		return newErrResult(err)
+	}
+	return q.ExecNonQuery()
+}
+
+// Delete deletes the row matching the passed in object's primary key.
+// Result.RowsAffected holds the number of deleted rows.
+func (s *Schema) Delete(obj interface{}) *Result {
+	_, primary := prepareFields(obj, false)
+	if primary == nil {
+		return newErrResult(errNoPrimaryKey)
+	}
+	return s.DeleteByPK(obj, primary.Value)
+}
+
+// DeleteByPK deletes the row with the given primary key from the table that
+// obj maps to. obj is only used to derive the table and primary key column.
+func (s *Schema) DeleteByPK(obj interface{}, id PrimaryKey) *Result {
+	sql, err := deleteSQL(obj, s.Name)
+	if err != nil {
+		return newErrResult(err)
+	}
+	return s.query(sql, id).ExecNonQuery()
+}
+
+// FindByPK fetches the row with the given primary key into dest, which must be
+// a pointer to a struct. The table and columns are derived from dest the same
+// way inserts are. Expects the row to exist or it will return an error.
+func (s *Schema) FindByPK(dest interface{}, id PrimaryKey) *Result {
+	sql, err := selectByPKSQL(dest, s.Name)
+	if err != nil {
+		return newErrResult(err)
+	}
+	return s.query(sql, id).ExecSingle(dest)
+}
+
 // generateInsertQuery constructs an insert query for the given object
 func (s *Schema) generateInsertQuery(obj interface{}, withPK bool) *Query {
 	sql := insertSQL(obj, s.Name, withPK)
 	args := insertArgs(obj, withPK)
-	q := s.Database.Query(sql, args...)
+	q := s.query(sql, args...)
 	q.insert = true
 	return q
 }
 
 // insertSQL generates insert SQL string for a given object and schema
 func insertSQL(obj interface{}, schema string, withPK bool) string {
-	// Construct the table name prefixed with schema name
-	tname := goToSQLName(getTypeName(obj))
-	tname = fmt.Sprintf("%s.%s", schema, tname)
+	sql := insertValuesSQL(obj, schema, withPK)
+
+	// Add last line to capture primary key
+	_, primary := prepareFields(obj, withPK)
+	sql += fmt.Sprintf("RETURNING %s as LastInsertId;", getColumnName(primary))
+
+	return sql
+}
 
+// insertValuesSQL generates the INSERT INTO ... VALUES (...) part of an insert
+// statement for a given object and schema, without a RETURNING clause.
+func insertValuesSQL(obj interface{}, schema string, withPK bool) string {
 	// Construct the first component of insert statement
-	sql := fmt.Sprintf("INSERT INTO %s (", tname)
+	sql := fmt.Sprintf("INSERT INTO %s (", tableName(obj, schema))
 
 	// NOTE: An object is represented as a slice of Fields,
 	// where each Field represents a column.
 	// Get list of columns to populate.
-	fields, primary := prepareFields(obj, withPK)
+	fields, _ := prepareFields(obj, withPK)
 
 	// Based on the number of columns, create value placeholders
 	var values string
@@ -113,12 +207,84 @@ func insertSQL(obj interface{}, schema string, withPK bool) string {
 	sql = strings.TrimRight(sql, ",")
 	sql += "\n)\n"
 
-	// Add last line to capture primary key
-	sql += fmt.Sprintf("RETURNING %s as LastInsertId;", getColumnName(primary))
-
 	return sql
 }
 
+// updateSQL generates update SQL string for a given object and schema.
+// It returns errNoPrimaryKey if the object has no primary key field.
+func updateSQL(obj interface{}, schema string) (string, error) {
+	fields, primary := prepareFields(obj, false)
+	if primary == nil {
+		return "", errNoPrimaryKey
+	}
+
+	// Set every non primary key column from its placeholder
+	sql := fmt.Sprintf("UPDATE %s SET", tableName(obj, schema))
+	for i, f := range fields {
+		sql += fmt.Sprintf("\n\t%s = $%v,", getColumnName(f), i+1)
+	}
+	sql = strings.TrimRight(sql, ",")
+
+	// The primary key is always the last placeholder
+	sql += fmt.Sprintf("\nWHERE %s = $%v;", getColumnName(primary), len(fields)+1)
+
+	return sql, nil
+}
+
+// updateArgs creates the update arg slice for an object. The primary key value
+// comes last to match the WHERE clause built by updateSQL.
+func updateArgs(obj interface{}) []interface{} {
+	fields, primary := prepareFields(obj, false)
+	args := make([]interface{}, 0, len(fields)+1)
+	for _, f := range fields {
+		args = append(args, f.Value)
+	}
+	if primary != nil {
+		args = append(args, primary.Value)
+	}
+	return args
+}
+
+// deleteSQL generates delete SQL string for a given object and schema.
+// The primary key value is expected as the only argument.
+func deleteSQL(obj interface{}, schema string) (string, error) {
+	_, primary := prepareFields(obj, false)
+	if primary == nil {
+		return "", errNoPrimaryKey
+	}
+
+	sql := fmt.Sprintf("DELETE FROM %s\nWHERE %s = $1;",
+		tableName(obj, schema), getColumnName(primary))
+
+	return sql, nil
+}
+
+// selectByPKSQL generates a select SQL string for a given object and schema
+// that fetches every column of a single row by primary key. The primary key
+// value is expected as the only argument.
+func selectByPKSQL(obj interface{}, schema string) (string, error) {
+	fields, primary := prepareFields(obj, true)
+	if primary == nil {
+		return "", errNoPrimaryKey
+	}
+
+	sql := "SELECT"
+	for _, f := range fields {
+		sql += fmt.Sprintf("\n\t%s,", getColumnName(f))
+	}
+	sql = strings.TrimRight(sql, ",")
+	sql += fmt.Sprintf("\nFROM %s\nWHERE %s = $1;",
+		tableName(obj, schema), getColumnName(primary))
+
+	return sql, nil
+}
+
+// tableName returns the table name for an object prefixed with the schema
+// name, e.g. a Book in the paper schema is paper.book
+func tableName(obj interface{}, schema string) string {
+	return fmt.Sprintf("%s.%s", schema, goToSQLName(getTypeName(obj)))
+}
+
 // getColumnName returns a Field's associated Tag name if it is supplied.
 // Else, it constructs a snake_case value from Field.Name value and returns it.
 // Example:
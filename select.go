@@ -0,0 +1,188 @@
+package papergres
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SelectQuery builds a SELECT statement for the table of a model struct. The
+// table and columns are derived from the model the same way inserts are, and
+// arguments are bound to `?` placeholders that are numbered $1, $2, etc. in
+// the order they are added. Use `??` for a literal question mark.
+//
+// Example usage:
+//
+//	var books []Book
+//	res := db.Schema("paper").Select(&Book{}).
+//		Where("author = ?", "Tolkien").
+//		Where("created_at > ?", since).
+//		OrderBy("title").
+//		Limit(10).
+//		ExecAll(&books)
+type SelectQuery struct {
+	schema *Schema
+	model  interface{}
+
+	joins   []string
+	where   []string
+	groupBy []string
+	orderBy []string
+	limit   int
+	offset  int
+	args    []interface{}
+
+	// err is the first error found while building the query.
+	err error
+}
+
+// Select starts a SELECT of every column of the table model maps to.
+func (s *Schema) Select(model interface{}) *SelectQuery {
+	return &SelectQuery{
+		schema: s,
+		model:  model,
+		limit:  -1,
+		offset: -1,
+	}
+}
+
+// Join adds a join clause, e.g. "JOIN paper.character ON character.book_id =
+// book.book_id". Its `?` placeholders are bound to args.
+func (sq *SelectQuery) Join(clause string, args ...interface{}) *SelectQuery {
+	sq.joins = append(sq.joins, sq.bind(clause, args))
+	return sq
+}
+
+// Where adds a condition, e.g. "author = ?". Each condition is parenthesized
+// and combined with AND. Its `?` placeholders are bound to args.
+func (sq *SelectQuery) Where(expr string, args ...interface{}) *SelectQuery {
+	sq.where = append(sq.where, "("+sq.bind(expr, args)+")")
+	return sq
+}
+
+// GroupBy adds columns or expressions to group by.
+func (sq *SelectQuery) GroupBy(exprs ...string) *SelectQuery {
+	sq.groupBy = append(sq.groupBy, exprs...)
+	return sq
+}
+
+// OrderBy adds columns or expressions to order by, e.g. "created_at DESC".
+func (sq *SelectQuery) OrderBy(exprs ...string) *SelectQuery {
+	sq.orderBy = append(sq.orderBy, exprs...)
+	return sq
+}
+
+// Limit sets the maximum number of rows to return.
+func (sq *SelectQuery) Limit(n int) *SelectQuery {
+	sq.limit = n
+	return sq
+}
+
+// Offset sets the number of rows to skip.
+func (sq *SelectQuery) Offset(n int) *SelectQuery {
+	sq.offset = n
+	return sq
+}
+
+// SQL returns the SELECT statement and its arguments.
+func (sq *SelectQuery) SQL() (string, []interface{}, error) {
+	if sq.err != nil {
+		return "", nil, sq.err
+	}
+
+	table := goToSQLName(getTypeName(sq.model))
+	fields, _ := prepareFields(sq.model, true)
+
+	// Qualify columns with the table so joined tables can share column names
+	sql := "SELECT"
+	for _, f := range fields {
+		sql += fmt.Sprintf("\n\t%s.%s,", table, getColumnName(f))
+	}
+	sql = strings.TrimRight(sql, ",")
+	sql += fmt.Sprintf("\nFROM %s", tableName(sq.model, sq.schema.Name))
+
+	for _, j := range sq.joins {
+		sql += "\n" + j
+	}
+	if len(sq.where) > 0 {
+		sql += "\nWHERE " + strings.Join(sq.where, "\n\tAND ")
+	}
+	if len(sq.groupBy) > 0 {
+		sql += "\nGROUP BY " + strings.Join(sq.groupBy, ", ")
+	}
+	if len(sq.orderBy) > 0 {
+		sql += "\nORDER BY " + strings.Join(sq.orderBy, ", ")
+	}
+	if sq.limit >= 0 {
+		sql += fmt.Sprintf("\nLIMIT %d", sq.limit)
+	}
+	if sq.offset >= 0 {
+		sql += fmt.Sprintf("\nOFFSET %d", sq.offset)
+	}
+	sql += ";"
+
+	return sql, sq.args, nil
+}
+
+// Query returns the built statement as a Query against the schema's database,
+// or its transaction when the schema was created from a Tx.
+func (sq *SelectQuery) Query() (*Query, error) {
+	sql, args, err := sq.SQL()
+	if err != nil {
+		return nil, err
+	}
+	return sq.schema.query(sql, args...), nil
+}
+
+// ExecAll gets every matching row and populates the given slice, see
+// Query.ExecAll.
+func (sq *SelectQuery) ExecAll(dest interface{}) *Result {
+	q, err := sq.Query()
+	if err != nil {
+		return newErrResult(err)
+	}
+	return q.ExecAll(dest)
+}
+
+// ExecSingle fetches the first matching row into dest, see Query.ExecSingle.
+func (sq *SelectQuery) ExecSingle(dest interface{}) *Result {
+	q, err := sq.Query()
+	if err != nil {
+		return newErrResult(err)
+	}
+	return q.ExecSingle(dest)
+}
+
+// bind replaces the `?` placeholders of expr with the $n placeholders of args
+// and adds args to the query. `??` is a literal question mark and question
+// marks in quoted strings and identifiers are left alone.
+func (sq *SelectQuery) bind(expr string, args []interface{}) string {
+	var b strings.Builder
+	var quote rune
+	n := 0
+
+	r := []rune(expr)
+	for i := 0; i < len(r); i++ {
+		c := r[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?' && i+1 < len(r) && r[i+1] == '?':
+			i++
+		case c == '?':
+			n++
+			b.WriteString(fmt.Sprintf("$%d", len(sq.args)+n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+
+	if n != len(args) && sq.err == nil {
+		sq.err = fmt.Errorf("%q has %d placeholders but %d args", expr, n, len(args))
+	}
+	sq.args = append(sq.args, args...)
+	return b.String()
+}
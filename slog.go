@@ -0,0 +1,54 @@
+package papergres
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewSlogLogger returns a StructuredLogger that writes to l, or to
+// slog.Default() when l is nil. Set it as StructuredLog to send papergres logs
+// to log/slog:
+//
+//	papergres.StructuredLog = papergres.NewSlogLogger(logger)
+func NewSlogLogger(l *slog.Logger) StructuredLogger {
+	return &slogLogger{l: l}
+}
+
+// slogLogger adapts a *slog.Logger to StructuredLogger.
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// Log writes the entry with every field as an attribute.
+func (s *slogLogger) Log(ctx context.Context, level Level, msg string, fields ...LogField) {
+	l := s.l
+	if l == nil {
+		l = slog.Default()
+	}
+
+	lvl := slogLevel(level)
+	if !l.Enabled(ctx, lvl) {
+		return
+	}
+
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+	l.LogAttrs(ctx, lvl, msg, attrs...)
+}
+
+// slogLevel maps a Level to the matching slog level.
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelInfo:
+		return slog.LevelInfo
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	}
+	return slog.Level(4 * (int(level) - int(LevelInfo)))
+}
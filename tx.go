@@ -0,0 +1,160 @@
+package papergres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Tx is an in-progress database transaction. Every Query and Schema created
+// from a Tx runs on the same underlying connection, so the work either
+// commits or rolls back as a single unit.
+//
+// A Tx must end with a call to Commit or Rollback. Use Database.InTx to have
+// that handled for you.
+type Tx struct {
+	Database *Database
+	tx       *sqlx.Tx
+}
+
+// Begin starts a new transaction on the database.
+func (db *Database) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// BeginTx starts a new transaction with the given options. The transaction is
+// rolled back by the driver if ctx is canceled before it is committed.
+func (db *Database) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	pool, err := db.pool()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := pool.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{
+		Database: db,
+		tx:       tx,
+	}, nil
+}
+
+// InTx runs fn inside a transaction. The transaction is committed if fn
+// returns nil and rolled back if fn returns an error or panics. A panic is
+// re-raised after the rollback.
+//
+// If the database has a RetryPolicy and the transaction fails with a
+// retryable error, such as a serialization failure, fn is run again in a new
+// transaction. fn should therefore not have side effects outside of tx.
+//
+// Example usage:
+//
+//	err := db.InTx(func(tx *Tx) error {
+//		res := tx.Schema("paper").Insert(book)
+//		if res.Err != nil {
+//			return res.Err
+//		}
+//		_, err := tx.Schema("paper").InsertAll(characters)
+//		return err
+//	})
+func (db *Database) InTx(fn func(tx *Tx) error) error {
+	for attempt := 1; ; attempt++ {
+		committing, err := db.inTx(fn)
+		// a commit that failed on a broken connection may have gone through
+		if !db.retry.shouldRetry(attempt, err, !committing) {
+			return err
+		}
+		logDebug(fmt.Sprintf("Transaction attempt %v / %v failed, retrying: %v",
+			attempt, db.retry.MaxAttempts, err))
+		sleep(context.Background(), db.retry.backoff(attempt))
+	}
+}
+
+// inTx runs a single attempt of InTx. committing reports whether err came
+// from the commit.
+func (db *Database) inTx(fn func(tx *Tx) error) (committing bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		committing = true
+		if err = tx.Commit(); err != nil {
+			// make sure the connection goes back to the pool even if the
+			// commit never reached the server
+			tx.tx.Rollback()
+		}
+	}()
+
+	return false, fn(tx)
+}
+
+// Commit commits the transaction.
+func (tx *Tx) Commit() error {
+	return tx.end("COMMIT", tx.tx.Commit, tx.tx.Rollback)
+}
+
+// Rollback aborts the transaction.
+func (tx *Tx) Rollback() error {
+	return tx.end("ROLLBACK", tx.tx.Rollback, tx.tx.Rollback)
+}
+
+// end runs the commit or rollback through execCommand so that it shows up in
+// the query log alongside the statements of the transaction. A hook can't keep
+// the transaction open: if one fails, fn is skipped and the transaction is
+// ended with rollback instead, so a failed COMMIT never writes anything.
+func (tx *Tx) end(sql string, fn, rollback func() error) error {
+	ended := false
+	err := execCommand(tx.Query(sql), func(ctx context.Context, r *Result) error {
+		ended = true
+		return fn()
+	}).Err
+	if !ended {
+		rollback()
+	}
+	return err
+}
+
+// Query creates a new query that runs inside the transaction.
+func (tx *Tx) Query(sql string, args ...interface{}) *Query {
+	q := tx.Database.Query(sql, args...)
+	q.tx = tx
+	return q
+}
+
+// Schema allows for schema specific operations inside the transaction.
+func (tx *Tx) Schema(name string) *Schema {
+	return &Schema{
+		Name:     name,
+		Database: tx.Database,
+		tx:       tx,
+	}
+}
+
+// GenerateInsert generates an insert query for the given object that runs
+// inside the transaction.
+func (tx *Tx) GenerateInsert(obj interface{}) *Query {
+	return tx.Schema("public").GenerateInsert(obj)
+}
+
+// Insert inserts the passed in object inside the transaction.
+func (tx *Tx) Insert(obj interface{}) *Result {
+	return tx.Schema("public").Insert(obj)
+}
+
+// InsertAll inserts a slice of objects inside the transaction.
+// The Result slice will be in the same order as objs.
+func (tx *Tx) InsertAll(objs interface{}) ([]*Result, error) {
+	return tx.Schema("public").InsertAll(objs)
+}
@@ -0,0 +1,166 @@
+package papergres
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// UpsertOptions controls the ON CONFLICT clause generated for an upsert.
+type UpsertOptions struct {
+	// ConflictColumns is the conflict target: the columns of a unique
+	// constraint or index, e.g. []string{"title"} for uc_book_title.
+	ConflictColumns []string
+
+	// UpdateColumns are the columns overwritten with the new row's values when
+	// a conflict occurs. Defaults to every inserted column that is not a
+	// conflict column.
+	UpdateColumns []string
+
+	// DoNothing leaves the existing row untouched when a conflict occurs.
+	// UpdateColumns is ignored when set.
+	DoNothing bool
+}
+
+// GenerateUpsert generates an INSERT ... ON CONFLICT query for the given
+// object. The query returns the primary key of the inserted row or, on
+// conflict, of the existing row.
+func (s *Schema) GenerateUpsert(obj interface{}, opts UpsertOptions) (*Query, error) {
+	sql, err := upsertSQL(obj, s.Name, opts)
+	if err != nil {
+		return nil, err
+	}
+	q := s.query(sql, insertArgs(obj, false)...)
+	q.insert = true
+	return q, nil
+}
+
+// Upsert inserts the passed in object or, if it conflicts with an existing
+// row on opts.ConflictColumns, updates that row instead (or leaves it alone
+// when opts.DoNothing is set).
+//
+// Result.LastInsertId holds the primary key of the affected row whether it
+// was inserted, updated or left untouched. Postgres draws a serial key's value
+// before it checks for a conflict, so an upsert that conflicts still uses one
+// up.
+func (s *Schema) Upsert(obj interface{}, opts UpsertOptions) *Result {
+	q, err := s.GenerateUpsert(obj, opts)
+	if err != nil {
+		return newErrResult(err)
+	}
+	return q.Exec()
+}
+
+// UpsertAll upserts a slice of objects with the same options.
+// objs must be a slice with items in it.
+// the Result slice will be in the same order as objs, each holding the primary
+// key of its row in LastInsertId.
+func (s *Schema) UpsertAll(objs interface{}, opts UpsertOptions) ([]*Result, error) {
+	slice, err := convertToSlice(objs)
+	if err != nil {
+		return nil, err
+	}
+	if len(slice) == 0 {
+		return nil, errors.New("empty slice")
+	}
+
+	q, err := s.GenerateUpsert(slice[0], opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// now turn the objs into a repeat query and exec
+	return q.Repeat(len(slice),
+		func(i int) (dest interface{}, args []interface{}) {
+			args = insertArgs(slice[i], false)
+			return
+		}).Exec()
+}
+
+// upsertSQL generates an INSERT ... ON CONFLICT SQL string for a given object
+// and schema.
+//
+// DO UPDATE returns the primary key for both inserted and updated rows.
+// DO NOTHING returns no row on conflict, so the statement is wrapped in a CTE
+// that falls back to looking up the existing row by its conflict columns.
+func upsertSQL(obj interface{}, schema string, opts UpsertOptions) (string, error) {
+	if len(opts.ConflictColumns) == 0 {
+		return "", errNoConflictColumns
+	}
+
+	fields, primary := prepareFields(obj, false)
+	if primary == nil {
+		return "", errNoPrimaryKey
+	}
+	pk := getColumnName(primary)
+
+	// map each inserted column to its placeholder so the conflict columns
+	// can be reused in the lookup of an existing row
+	placeholders := make(map[string]int, len(fields))
+	var columns []string
+	for i, f := range fields {
+		name := getColumnName(f)
+		placeholders[name] = i + 1
+		columns = append(columns, name)
+	}
+
+	sql := insertValuesSQL(obj, schema, false)
+	sql += fmt.Sprintf("ON CONFLICT (%s) ", strings.Join(opts.ConflictColumns, ", "))
+
+	if !opts.DoNothing {
+		update := opts.UpdateColumns
+		if len(update) == 0 {
+			update = without(columns, opts.ConflictColumns)
+		}
+		if len(update) == 0 {
+			return "", errNoUpdateColumns
+		}
+
+		sql += "DO UPDATE SET"
+		for _, c := range update {
+			sql += fmt.Sprintf("\n\t%s = EXCLUDED.%s,", c, c)
+		}
+		sql = strings.TrimRight(sql, ",")
+		sql += fmt.Sprintf("\nRETURNING %s as LastInsertId;", pk)
+		return sql, nil
+	}
+
+	var where []string
+	for _, c := range opts.ConflictColumns {
+		n, ok := placeholders[c]
+		if !ok {
+			return "", fmt.Errorf("conflict column %s is not an inserted column", c)
+		}
+		where = append(where, fmt.Sprintf("%s = $%v", c, n))
+	}
+
+	sql = fmt.Sprintf(`WITH ins AS (
+%sDO NOTHING
+RETURNING %s
+)
+SELECT %s as LastInsertId FROM ins
+UNION ALL
+SELECT %s FROM %s WHERE %s
+LIMIT 1;`,
+		sql, pk, pk, pk, tableName(obj, schema), strings.Join(where, " AND "))
+
+	return sql, nil
+}
+
+// without returns the items of s that are not in exclude, keeping their order.
+func without(s []string, exclude []string) []string {
+	var out []string
+	for _, v := range s {
+		found := false
+		for _, e := range exclude {
+			if v == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, v)
+		}
+	}
+	return out
+}